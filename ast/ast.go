@@ -1,9 +1,12 @@
-package main
+// Package ast defines the syntax tree of Duet programs.
+package ast
 
 import (
 	"bytes"
 	"fmt"
 	"strings"
+
+	"duet/token"
 )
 
 // Node is the base interface for all AST nodes.
@@ -40,14 +43,21 @@ func (p *Program) String() string {
 	var out bytes.Buffer
 	for _, s := range p.Statements {
 		out.WriteString(s.String())
+		out.WriteString("\n")
 	}
 	return out.String()
 }
 
 // Identifier represents an identifier.
 type Identifier struct {
-	Token Token // the token.IDENT token
+	Token token.Token // the token.IDENT token
 	Value string
+
+	// Lexical address filled in by the Resolver. Local identifiers live in
+	// slot Slot of the memory Depth scopes out; the others are looked up by name.
+	Local bool
+	Depth int
+	Slot  int
 }
 
 func (i *Identifier) expressionNode()      {}
@@ -60,13 +70,62 @@ type Parameter struct {
 	Type *Identifier
 }
 
+// Annotation represents an `@name` or `@name(args)` annotation on a function definition.
+type Annotation struct {
+	Token     token.Token // The '@' token
+	Name      *Identifier
+	Arguments []Expression
+}
+
+func (a *Annotation) String() string {
+	var out bytes.Buffer
+	out.WriteString("@" + a.Name.String())
+	if len(a.Arguments) > 0 {
+		args := []string{}
+		for _, arg := range a.Arguments {
+			args = append(args, arg.String())
+		}
+		out.WriteString("(" + strings.Join(args, ", ") + ")")
+	}
+	return out.String()
+}
+
+// DEFAULT_MEMO_SIZE is the number of results a `@memo` proc keeps when no size is given.
+const DEFAULT_MEMO_SIZE = 1024
+
+// memoSize returns the cache size requested by a `@memo` annotation.
+func MemoSize(a *Annotation) (int, bool) {
+	switch len(a.Arguments) {
+	case 0:
+		return DEFAULT_MEMO_SIZE, true
+	case 1:
+		size, ok := a.Arguments[0].(*IntegerLiteral)
+		if !ok || size.Value <= 0 {
+			return 0, false
+		}
+		return int(size.Value), true
+	}
+	return 0, false
+}
+
 // FunctionStatement represents a function definition (proc, cons, supp, etc.).
 type FunctionStatement struct {
-	Token      Token        // The function type token (e.g., PROC)
-	Name       *Identifier  // The name of the function
-	Parameters []*Parameter // The parameters of the function
-	ReturnType *Identifier  // The return type of the function
-	Body       Expression   // The body of the function
+	Token       token.Token   // The function type token (e.g., PROC)
+	Annotations []*Annotation // Annotations written before the definition, e.g. @memo
+	Name        *Identifier   // The name of the function
+	Parameters  []*Parameter  // The parameters of the function
+	ReturnType  *Identifier   // The return type of the function
+	Body        Expression    // The body of the function
+}
+
+// Annotation returns the annotation with the given name, or nil.
+func (fs *FunctionStatement) Annotation(name string) *Annotation {
+	for _, a := range fs.Annotations {
+		if a.Name.Value == name {
+			return a
+		}
+	}
+	return nil
 }
 
 func (fs *FunctionStatement) statementNode()       {}
@@ -79,6 +138,9 @@ func (fs *FunctionStatement) String() string {
 		params = append(params, p.Name.String()+": "+p.Type.String())
 	}
 
+	for _, a := range fs.Annotations {
+		out.WriteString(a.String() + " ")
+	}
 	out.WriteString(fs.TokenLiteral() + " ")
 	out.WriteString(fs.Name.String())
 	if len(fs.Parameters) > 0 {
@@ -97,7 +159,7 @@ func (fs *FunctionStatement) String() string {
 
 // FailExpression represents the 'fail' keyword, which produces an error.
 type FailExpression struct {
-	Token   Token // The 'fail' token
+	Token   token.Token // The 'fail' token
 	Message string
 }
 
@@ -108,9 +170,100 @@ func (fe *FailExpression) String() string {
 	return fmt.Sprintf("fail %s", fe.Message)
 }
 
+// YieldExpression represents `yield value`, which emits value from a generator supp.
+type YieldExpression struct {
+	Token token.Token // The 'yield' token
+	Value Expression
+}
+
+func (ye *YieldExpression) expressionNode()      {}
+func (ye *YieldExpression) TokenLiteral() string { return ye.Token.Literal }
+func (ye *YieldExpression) String() string {
+	return "yield " + ye.Value.String()
+}
+
+// SpawnExpression runs a call to a cons on a new goroutine: `spawn pump(in, out)`.
+type SpawnExpression struct {
+	Token token.Token // The 'spawn' token
+	Call  *CallExpression
+}
+
+func (se *SpawnExpression) expressionNode()      {}
+func (se *SpawnExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SpawnExpression) String() string {
+	return "spawn " + se.Call.String()
+}
+
+// SelectExpression waits until one of its channel operations can proceed.
+type SelectExpression struct {
+	Token   token.Token // The 'select' token
+	Cases   []*SelectCase
+	Default Expression // Runs when no case is ready; nil makes select wait
+}
+
+// SelectCase is one `is recv(ch) as x then ...` or `is send(ch, v) then ...` case.
+// Call is kept as written so that walkers see the recv or send builtin it uses.
+type SelectCase struct {
+	Call     *CallExpression
+	Variable *Identifier // The name bound to the received value, or nil
+	Body     Expression
+}
+
+// IsSend reports whether the case sends rather than receives.
+func (sc *SelectCase) IsSend() bool {
+	return sc.Call.Function.(*Identifier).Value == "send"
+}
+
+func (se *SelectExpression) expressionNode()      {}
+func (se *SelectExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SelectExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("select {")
+	for _, c := range se.Cases {
+		out.WriteString("\nis " + c.Call.String())
+		if c.Variable != nil {
+			out.WriteString(" as " + c.Variable.String())
+		}
+		out.WriteString(" then " + c.Body.String())
+	}
+	if se.Default != nil {
+		out.WriteString("\ndefault " + se.Default.String())
+	}
+	out.WriteString("\n}")
+	return out.String()
+}
+
+// ImportStatement binds a module to a name: `import "lib/text.duet" as text`.
+type ImportStatement struct {
+	Token token.Token // The 'import' token
+	Path  string      // The path as written
+	Name  *Identifier // The name after `as`, or one derived from Path
+	// Module is the loaded module, filled in when the program is compiled.
+	Module any
+}
+
+func (is *ImportStatement) statementNode()       {}
+func (is *ImportStatement) TokenLiteral() string { return is.Token.Literal }
+func (is *ImportStatement) String() string {
+	return fmt.Sprintf("import %q as %s", is.Path, is.Name.String())
+}
+
+// MemberExpression refers to a function exported by an imported module: `text.normalize`.
+type MemberExpression struct {
+	Token  token.Token // The '.' token
+	Module *Identifier
+	Member *Identifier
+}
+
+func (me *MemberExpression) expressionNode()      {}
+func (me *MemberExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MemberExpression) String() string {
+	return me.Module.String() + "." + me.Member.String()
+}
+
 // ExpressionStatement wraps an expression so it can be used as a statement.
 type ExpressionStatement struct {
-	Token      Token // the first token of the expression
+	Token      token.Token // the first token of the expression
 	Expression Expression
 }
 
@@ -125,7 +278,7 @@ func (es *ExpressionStatement) String() string {
 
 // IntegerLiteral represents an integer literal.
 type IntegerLiteral struct {
-	Token Token
+	Token token.Token
 	Value int64
 }
 
@@ -135,7 +288,7 @@ func (il *IntegerLiteral) String() string       { return il.Token.Literal }
 
 // FloatLiteral represents a float literal.
 type FloatLiteral struct {
-	Token Token
+	Token token.Token
 	Value float64
 }
 
@@ -145,7 +298,7 @@ func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
 
 // StringLiteral represents a string literal.
 type StringLiteral struct {
-	Token Token
+	Token token.Token
 	Value string
 }
 
@@ -155,7 +308,7 @@ func (sl *StringLiteral) String() string       { return sl.Token.Literal }
 
 // BooleanLiteral represents a boolean literal.
 type BooleanLiteral struct {
-	Token Token
+	Token token.Token
 	Value bool
 }
 
@@ -165,7 +318,7 @@ func (b *BooleanLiteral) String() string       { return b.Token.Literal }
 
 // NilLiteral represents a nil literal.
 type NilLiteral struct {
-	Token Token
+	Token token.Token
 }
 
 func (n *NilLiteral) expressionNode()      {}
@@ -174,7 +327,7 @@ func (n *NilLiteral) String() string       { return "nil" }
 
 // PrefixExpression represents an expression with a prefix operator.
 type PrefixExpression struct {
-	Token    Token // The prefix token, e.g. !
+	Token    token.Token // The prefix token, e.g. !
 	Operator string
 	Right    Expression
 }
@@ -192,7 +345,7 @@ func (pe *PrefixExpression) String() string {
 
 // InfixExpression represents an expression with an infix operator.
 type InfixExpression struct {
-	Token    Token // The operator token, e.g. +
+	Token    token.Token // The operator token, e.g. +
 	Left     Expression
 	Operator string
 	Right    Expression
@@ -210,9 +363,15 @@ func (ie *InfixExpression) String() string {
 	return out.String()
 }
 
+// IsPipeline reports whether the expression is a `|>` or `||>` pipeline, whose right
+// side is called with the value of its left side.
+func (ie *InfixExpression) IsPipeline() bool {
+	return ie.Operator == "|>" || ie.Operator == "||>"
+}
+
 // IfExpression represents an if-then-else expression.
 type IfExpression struct {
-	Token       Token // The 'if' token
+	Token       token.Token // The 'if' token
 	Condition   Expression
 	Consequence Expression
 	Alternative Expression
@@ -233,11 +392,36 @@ func (ie *IfExpression) String() string {
 	return out.String()
 }
 
-// ForExpression represents a for-in expression.
-type ForExpression struct {
-	Token      Token // The 'for' token
+// ForGenerator represents one `x in xs` clause of a for expression, with an optional `if` guard.
+// With two variables (`k, v in m`), Key receives the map key or list index.
+type ForGenerator struct {
+	Key        *Identifier
 	Variable   *Identifier
 	Collection Expression
+	Condition  Expression
+}
+
+func (fg *ForGenerator) String() string {
+	var out bytes.Buffer
+	if fg.Key != nil {
+		out.WriteString(fg.Key.String() + ", ")
+	}
+	out.WriteString(fg.Variable.String())
+	out.WriteString(" in ")
+	out.WriteString(fg.Collection.String())
+	if fg.Condition != nil {
+		out.WriteString(" if ")
+		out.WriteString(fg.Condition.String())
+	}
+	return out.String()
+}
+
+// ForExpression represents a for-in comprehension.
+// When MapKey is set (`then k: v`), the expression builds a map instead of a list.
+type ForExpression struct {
+	Token      token.Token // The 'for' token
+	Generators []*ForGenerator
+	MapKey     Expression
 	Body       Expression
 }
 
@@ -245,18 +429,23 @@ func (fe *ForExpression) expressionNode()      {}
 func (fe *ForExpression) TokenLiteral() string { return fe.Token.Literal }
 func (fe *ForExpression) String() string {
 	var out bytes.Buffer
+	generators := []string{}
+	for _, g := range fe.Generators {
+		generators = append(generators, g.String())
+	}
 	out.WriteString("for ")
-	out.WriteString(fe.Variable.String())
-	out.WriteString(" in ")
-	out.WriteString(fe.Collection.String())
+	out.WriteString(strings.Join(generators, ", "))
 	out.WriteString(" then ")
+	if fe.MapKey != nil {
+		out.WriteString(fe.MapKey.String() + ": ")
+	}
 	out.WriteString(fe.Body.String())
 	return out.String()
 }
 
 // CallExpression represents a function call.
 type CallExpression struct {
-	Token     Token // The '(' token
+	Token     token.Token // The '(' token
 	Function  Expression
 	Arguments []Expression
 }
@@ -281,7 +470,7 @@ func (ce *CallExpression) String() string {
 
 // ListLiteral represents a list literal.
 type ListLiteral struct {
-	Token    Token // the '[' token
+	Token    token.Token // the '[' token
 	Elements []Expression
 }
 
@@ -303,7 +492,7 @@ func (ll *ListLiteral) String() string {
 
 // MapLiteral represents a map literal.
 type MapLiteral struct {
-	Token Token // the '{' token
+	Token token.Token // the '{' token
 	Pairs map[Expression]Expression
 }
 
@@ -326,7 +515,7 @@ func (ml *MapLiteral) String() string {
 
 // IndexExpression represents an index expression (e.g., list[index] or map[key]).
 type IndexExpression struct {
-	Token Token // The '[' token
+	Token token.Token // The '[' token
 	Left  Expression
 	Index Expression
 }
@@ -353,7 +542,7 @@ type MatchCase struct {
 
 // MatchExpression represents a match expression.
 type MatchExpression struct {
-	Token   Token // The 'match' token
+	Token   token.Token // The 'match' token
 	Subject Expression
 	Cases   []*MatchCase
 	Default Expression // The default case (wildcard '_')
@@ -383,3 +572,83 @@ func (me *MatchExpression) String() string {
 	out.WriteString("\n}")
 	return out.String()
 }
+
+// Inspect traverses the expressions under node in depth-first order, calling f for
+// each one. If f returns false, the children of that node are skipped. Parameters,
+// function names, for generator variables and the names in a MemberExpression are
+// not visited.
+func Inspect(node Node, f func(Node) bool) {
+	if node == nil || !f(node) {
+		return
+	}
+	switch node := node.(type) {
+	case *Program:
+		for _, stmt := range node.Statements {
+			Inspect(stmt, f)
+		}
+	case *ExpressionStatement:
+		Inspect(node.Expression, f)
+	case *FunctionStatement:
+		Inspect(node.Body, f)
+	case *PrefixExpression:
+		Inspect(node.Right, f)
+	case *YieldExpression:
+		Inspect(node.Value, f)
+	case *SpawnExpression:
+		Inspect(node.Call, f)
+	case *SelectExpression:
+		for _, c := range node.Cases {
+			Inspect(c.Call, f)
+			Inspect(c.Body, f)
+		}
+		if node.Default != nil {
+			Inspect(node.Default, f)
+		}
+	case *InfixExpression:
+		Inspect(node.Left, f)
+		Inspect(node.Right, f)
+	case *IfExpression:
+		Inspect(node.Condition, f)
+		Inspect(node.Consequence, f)
+		if node.Alternative != nil {
+			Inspect(node.Alternative, f)
+		}
+	case *MatchExpression:
+		Inspect(node.Subject, f)
+		for _, c := range node.Cases {
+			Inspect(c.Condition, f)
+			Inspect(c.Consequence, f)
+		}
+		if node.Default != nil {
+			Inspect(node.Default, f)
+		}
+	case *ForExpression:
+		for _, gen := range node.Generators {
+			Inspect(gen.Collection, f)
+			if gen.Condition != nil {
+				Inspect(gen.Condition, f)
+			}
+		}
+		if node.MapKey != nil {
+			Inspect(node.MapKey, f)
+		}
+		Inspect(node.Body, f)
+	case *CallExpression:
+		Inspect(node.Function, f)
+		for _, arg := range node.Arguments {
+			Inspect(arg, f)
+		}
+	case *ListLiteral:
+		for _, el := range node.Elements {
+			Inspect(el, f)
+		}
+	case *MapLiteral:
+		for key, value := range node.Pairs {
+			Inspect(key, f)
+			Inspect(value, f)
+		}
+	case *IndexExpression:
+		Inspect(node.Left, f)
+		Inspect(node.Index, f)
+	}
+}
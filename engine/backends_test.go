@@ -0,0 +1,372 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"duet/object"
+)
+
+// backends are the ways a script can be run; every program must behave the same
+// on all of them.
+var backends = []struct {
+	name     string
+	useVM    bool
+	optimize bool
+}{
+	{"tree", false, false},
+	{"vm", true, false},
+	{"tree-optimized", false, true},
+	{"vm-optimized", true, true},
+}
+
+// runScript compiles and runs source and returns what it printed followed by its
+// result, or the compile or run error.
+func runScript(t testing.TB, source string, options EngineOptions, optimize bool) string {
+	t.Helper()
+	var out bytes.Buffer
+	if options.IO == nil {
+		options.IO = &object.IO{Stdout: &out, Stderr: &out, FS: object.NewMemFS(nil)}
+	}
+	e := New(options)
+	script, err := CompileScript(source, CompileOptions{Optimize: optimize, Globals: e.Memory})
+	if err != nil {
+		return "compile error: " + err.Error()
+	}
+	result, err := e.Exec(context.Background(), script)
+	if err != nil {
+		return out.String() + "error: " + err.Error()
+	}
+	return out.String() + "=> " + result.Inspect()
+}
+
+var programs = []struct {
+	name   string
+	source string
+	want   string
+}{
+	{"arithmetic", `1 + 2 * 3 - 8 / 4 % 3`, "=> 5"},
+	{"floats", `1.5 * 2.0 + 0.25`, "=> 3.250000"},
+	{"comparison", `[1 < 2, 2 <= 2, 3 >= 4, 1 == 1, 1 != 1, !true]`, "=> [true, true, false, true, false, false]"},
+	{"strings", `upper("ab") + "c" * 2 + string(len("xyz"))`, "=> ABcc3"},
+	{"string type error", `"a" + 1`, "error: type mismatch: STRING + INTEGER"},
+	{"if and match", `
+proc grade(n:int):str -> match n {
+is n >= 90 then "A"
+is n >= 80 then "B"
+default "F"
+}
+supp result:list -> [grade(95), grade(85), grade(10), if 1 > 2 then "x" else "y"]
+result`, "=> [A, B, F, y]"},
+	{"recursion", `
+proc fib(n:int):int -> if n < 2 then n else fib(n - 1) + fib(n - 2)
+fib(15)`, "=> 610"},
+	{"tail calls", `
+proc sum(xs:list, acc:int):int -> if len(xs) == 0 then acc else sum(rest(xs), acc + first(xs))
+sum(range(20000), 0)`, "=> 199990000"},
+	{"memo", `
+@memo proc fib(n:int):int -> if n < 2 then n else fib(n - 1) + fib(n - 2)
+fib(80)`, "=> 23416728348467685"},
+	{"for comprehensions", `
+proc pairs(xs:list):list -> for x in xs, y in xs if x < y then [x, y]
+proc squares(xs:list):map -> for x in xs then x: x * x
+supp result:list -> [pairs([1, 2, 3]), squares([2, 3]), for k, v in {"a": 1, "b": 2} then k + string(v)]
+result`,
+		"=> [[[1, 2], [1, 3], [2, 3]], {2: 4, 3: 9}, [a1, b2]]"},
+	{"fused for does not capture names", `for y in (for x in [1, 2, 3] then x) then for x in [10] then x + y`, "=> [[11], [12], [13]]"},
+	{"fused for does not capture names in conditions", `
+proc f(xs:list):list -> for y in (for x in xs then x) if len(for x in [5, 6] if x == y then x) > 0 then y
+f([1, 5, 6])`, "=> [5, 6]"},
+	{"pipelines", `
+proc even(x:int):bool -> x % 2 == 0
+proc double(x:int):int -> x * 2
+proc add(a:int, b:int):int -> a + b
+range(10) |> filter(even) |> map(double) |> reduce(add, 0)`, "=> 40"},
+	{"higher-order", `
+proc neg(x:int):int -> 0 - x
+supp result:list -> [sort_by([3, 1, 2], neg), group_by(["a", "bb", "c"], len), any([1, 2], neg), find([1, 2, 3], neg)]
+result`,
+		"=> [[3, 2, 1], {1: [a, c], 2: [bb]}, true, 1]"},
+	{"maps", `
+supp m:map -> {"b": 2, "a": 1}
+supp result:list -> [keys(m), values(put(m, "c", 3)), has(remove(m, "a"), "a"), merge(m, {"a": 9})]
+result`,
+		"=> [[a, b], [1, 2, 3], false, {a: 9, b: 2}]"},
+	{"print", `
+cons show(xs:list) -> for x in xs then print(x)
+show(range(1, 3))`, "1\n2\n=> [nil, nil]"},
+	{"generators", `
+supp evens:stream -> for i in range(6) if i % 2 == 0 then yield i
+proc inc(x:int):int -> x + 1
+collect(evens |> inc)`, "=> [1, 3, 5]"},
+	{"parallel pipeline", `
+proc square(x:int):int -> x * x
+range(5) ||> square`, "=> [0, 1, 4, 9, 16]"},
+	{"tee and fanout", `
+cons show(x:int) -> print(x)
+proc inc(x:int):int -> x + 1
+proc dec(x:int):int -> x - 1
+supp result:list -> [1 |> tee(show) |> inc, fanout(5, inc, dec), fanout(5, {"up": inc})]
+result`, "1\n=> [2, [6, 4], {up: 6}]"},
+	{"channels", `
+cons produce(out:chan, n:int) -> if n == 0 then close(out) else [send(out, n), produce(out, n - 1)]
+cons total(src:chan, acc:int) -> add(src, recv(src), acc)
+cons add(src:chan, v:int?, acc:int) -> if is_fail(v) then print(acc) else total(src, acc + v)
+cons main(c:chan) -> [spawn produce(c, 5), total(c, 0)]
+main(chan())`, "15\n=> [nil, nil]"},
+	{"fail", `
+proc safe(s:str?):str -> if is_fail(s) then "failed" else s
+supp result:list -> [safe(read("missing.txt")), is_fail(fail "x"), fail_code(fail "x")]
+result`, "=> [failed, true, ]"},
+	{"json numbers out of range", `supp result:list -> [json_parse("1E400"), json_parse("[2, [-1e999]]"), json_parse("1.5E3")]
+result`, "=> [could not parse JSON: number 1E400 is out of range, could not parse JSON: number -1e999 is out of range, 1500.000000]"},
+	{"json colliding keys", `supp result:list -> [json_stringify({1: "a", "1": "b"}), json_stringify({"t": {true: 1, "true": 2}}), json_stringify({1: "a", "2": "b"})]
+result`, `=> [could not convert to JSON: map keys 1 (INTEGER) and 1 (STRING) are both written as "1", could not convert to JSON: map keys true (BOOLEAN) and true (STRING) are both written as "true", {"1":"a","2":"b"}]`},
+	{"std with mixed ints and floats", `
+import "std/list"
+import "std/math"
+supp result:list -> [list.sum([1, 2.5]), list.product([2, 1.5]), list.min([3.5, 1]), list.max([3.5, 1, 4]), math.min(1, 0.5), math.max(2, 1.5), math.clamp(5, 0, 2.5), math.mean([1, 2.5]), list.sum([1, 2])]
+result`, "=> [3.500000, 3.000000, 1, 4, 0.500000, 2, 2.500000, 1.750000, 3]"},
+	{"identifier not found", `proc f(x:int):int -> y`, "compile error: identifier not found: y"},
+	{"effect error", `
+cons log(x:int) -> print(x)
+proc bad(x:int):int -> log(x)`, "compile error: effect error: proc bad calls cons log"},
+	{"memo rejects stream parameters", `@memo proc cnt(s:stream):int -> len(collect(s))`,
+		"compile error: @memo proc cnt cannot take a stream parameter: s"},
+	{"memo does not cache streams", `
+supp three:stream -> for i in range(3) then yield i
+supp five:stream -> for i in range(5) then yield i
+@memo proc cnt(s:any):int -> len(collect(s))
+supp result:list -> [cnt(five), cnt(three)]
+result`, "=> [5, 3]"},
+	{"memo does not cache functions", `
+@memo proc ap(f:fn, x:any):any -> f(x)
+supp result:list -> [ap(len, "abc"), ap(upper, "abc")]
+result`, "=> [3, ABC]"},
+	{"effects through fn parameters", `
+proc runit(f:fn, s:str):any -> f("pwn.txt", s)
+runit(write, "x")`, "error: effect error: proc runit calls an effectful builtin"},
+	{"memo does not swallow effects", `
+@memo proc ap(f:fn, x:int):any -> f(x)
+supp result:list -> [ap(print, 1), ap(print, 1)]
+result`, "error: effect error: proc ap calls an effectful builtin"},
+	{"cons through higher-order builtins", `
+cons show(x:int) -> print(x)
+proc each(f:fn, xs:list):list -> map(xs, f)
+proc id(x:int):int -> x
+supp result:list -> [each(id, [1, 2]), each(show, [1, 2])]
+result`, "error: effect error: proc each calls cons show"},
+	{"cons through tail calls", `
+cons show(x:int) -> print(x)
+proc apply(f:fn, x:int):any -> f(x)
+apply(show, 3)`, "error: effect error: proc apply calls cons show"},
+	{"runtime type error", `
+proc f(x:int):int -> x
+f("a")`, "error: type error: wrong type for argument x. got=STRING, want=int"},
+}
+
+func TestBackendsAgree(t *testing.T) {
+	for _, p := range programs {
+		t.Run(p.name, func(t *testing.T) {
+			for _, b := range backends {
+				got := runScript(t, p.source, EngineOptions{UseVM: b.useVM}, b.optimize)
+				if got != p.want {
+					t.Errorf("%s:\ngot  %q\nwant %q", b.name, got, p.want)
+				}
+			}
+		})
+	}
+}
+
+func TestStackOverflow(t *testing.T) {
+	const source = `
+proc down(n:int):int -> 1 + down(n - 1)
+down(100)`
+	for _, b := range backends {
+		got := runScript(t, source, EngineOptions{UseVM: b.useVM, MaxDepth: 50}, b.optimize)
+		if !strings.Contains(got, "stack overflow: maximum call depth 50 exceeded while calling down") {
+			t.Errorf("%s: got %q", b.name, got)
+		}
+	}
+}
+
+func benchmarkBackends(b *testing.B, source string) {
+	for _, backend := range backends[:2] {
+		b.Run(backend.name, func(b *testing.B) {
+			e := New(EngineOptions{UseVM: backend.useVM, IO: &object.IO{}})
+			script, err := CompileScript(source, CompileOptions{Globals: e.Memory})
+			if err != nil {
+				b.Fatal(err)
+			}
+			for b.Loop() {
+				if _, err := e.Exec(context.Background(), script); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkFib(b *testing.B) {
+	benchmarkBackends(b, `
+proc fib(n:int):int -> if n < 2 then n else fib(n - 1) + fib(n - 2)
+fib(20)`)
+}
+
+func BenchmarkTailRecursion(b *testing.B) {
+	benchmarkBackends(b, `
+proc count(n:int, acc:int):int -> if n == 0 then acc else count(n - 1, acc + n)
+count(100000, 0)`)
+}
+
+func BenchmarkListPipeline(b *testing.B) {
+	benchmarkBackends(b, `
+proc even(x:int):bool -> x % 2 == 0
+proc double(x:int):int -> x * 2
+proc add(a:int, b:int):int -> a + b
+range(100000) |> filter(even) |> map(double) |> reduce(add, 0)`)
+}
+
+func BenchmarkForComprehension(b *testing.B) {
+	benchmarkBackends(b, `
+proc squares(xs:list):list -> for x in xs if x % 3 == 0 then x * x
+len(squares(range(100000)))`)
+}
+
+func TestAllocBudget(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`len(range(3000000000))`, "error: budget exceeded: allocation of size 3000000000 exceeds limit of 1000"},
+		{`len(range(0 - 9000000000000000000, 9000000000000000000))`, "error: budget exceeded: allocation of size 9223372036854775807 exceeds limit of 1000"},
+		{`len("x" * 3000000000)`, "error: budget exceeded: allocation of size 3000000000 exceeds limit of 1000"},
+		{`len(join(for i in range(200) then "abcd", ","))`, "=> 999"},
+		{`len(join(for i in range(250) then "abcd", ","))`, "error: budget exceeded: allocation of size 1249 exceeds limit of 1000"},
+		{`len(replace("aaaaaaaaaa", "a", "x" * 101))`, "error: budget exceeded: allocation of size 1010 exceeds limit of 1000"},
+		{`
+supp evens:stream -> for i in range(1000) if i % 2 == 0 then yield i
+proc show(x:int):str -> string(x)
+len(collect(evens |> show))`, "=> 500"},
+		{`
+supp pairs:stream -> for i in range(40), j in range(40) then yield i
+len(collect(pairs))`, "error: budget exceeded: stream has more than 1000 elements"},
+	}
+	for _, tt := range tests {
+		for _, b := range backends {
+			got := runScript(t, tt.source, EngineOptions{UseVM: b.useVM, MaxAllocSize: 1000}, b.optimize)
+			if got != tt.want {
+				t.Errorf("%s: %s:\ngot  %q\nwant %q", b.name, tt.source, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestRangeLen(t *testing.T) {
+	for _, bounds := range [][3]int64{{0, 10, 1}, {0, 10, 3}, {10, 0, -3}, {5, 5, 1}, {5, 0, 1}, {-7, 7, 2}, {3, -4, -1}} {
+		want := 0
+		for i := bounds[0]; (bounds[2] > 0 && i < bounds[1]) || (bounds[2] < 0 && i > bounds[1]); i += bounds[2] {
+			want++
+		}
+		if got := rangeLen(bounds[0], bounds[1], bounds[2]); got != want {
+			t.Errorf("rangeLen%v = %d, want %d", bounds, got, want)
+		}
+	}
+}
+
+func TestRangeNearIntLimits(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`range(9223372036854775800, 9223372036854775807, 5)`, "=> [9223372036854775800, 9223372036854775805]"},
+		{`range(9223372036854775805, 9223372036854775807)`, "=> [9223372036854775805, 9223372036854775806]"},
+		{`range(0 - 9223372036854775800, 0 - 9223372036854775807, 0 - 5)`, "=> [-9223372036854775800, -9223372036854775805]"},
+		{`range(0 - 9223372036854775807, 0 - 9223372036854775807 - 1, 0 - 1)`, "=> [-9223372036854775807]"},
+	}
+	for _, tt := range tests {
+		for _, b := range backends {
+			got := runScript(t, tt.source, EngineOptions{UseVM: b.useVM}, b.optimize)
+			if got != tt.want {
+				t.Errorf("%s: %s:\ngot  %q\nwant %q", b.name, tt.source, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`proc count(xs:list):int -> len(xs)
+count(lines("in.txt"))`, "=> 3"},
+		{`supp src:list -> lines("in.txt")
+len(src)`, "=> 3"},
+		{`proc count(xs:list):int -> len(xs)
+lines("in.txt") |> count`, "=> 3"},
+		{`proc count(xs:list):int -> len(xs)
+stream_lines("in.txt") |> count`, "=> 3"},
+		{`proc shout(line:str):str -> upper(line)
+collect(stream_lines("in.txt") |> shout)`, "=> [A, B, C]"},
+		{`supp src:stream -> stream_lines("in.txt")
+len(src)`, "=> 3"},
+		{`lines("missing.txt")`, "=> could not open file: open missing.txt: file does not exist"},
+	}
+	for _, tt := range tests {
+		for _, b := range backends {
+			var out bytes.Buffer
+			io := &object.IO{Stdout: &out, FS: object.NewMemFS(map[string]string{"in.txt": "a\nb\nc\n"})}
+			got := runScript(t, tt.source, EngineOptions{UseVM: b.useVM, IO: io}, b.optimize)
+			if got != tt.want {
+				t.Errorf("%s: %s:\ngot  %q\nwant %q", b.name, tt.source, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestParallelPipelineCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, source := range []string{"5 ||> sq", "len(range(200) ||> sq)"} {
+		for _, b := range backends {
+			e := New(EngineOptions{UseVM: b.useVM, IO: &object.IO{}})
+			script, err := CompileScript("proc sq(x:int):int -> x * x\n"+source, CompileOptions{Optimize: b.optimize, Globals: e.Memory})
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := e.Exec(ctx, script)
+			if err == nil || err.Error() != "budget exceeded: execution cancelled" {
+				t.Errorf("%s: %s: got %v, %v; want execution cancelled", b.name, source, result, err)
+			}
+		}
+	}
+}
+
+func TestStepBudget(t *testing.T) {
+	const spin = `cons spin(n:int) -> if n == 0 then nil else spin(n - 1)
+`
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{spin + `spin(5000)`, "=> nil"},
+		{spin + `spin(20000)`, "error: budget exceeded: step limit of 50000 exceeded"},
+		{spin + `cons main(n:int) -> [spawn spin(n), spawn spin(n), spawn spin(n), spawn spin(n)]
+main(1000)`, "=> [nil, nil, nil, nil]"},
+		{spin + `cons main(n:int) -> [spawn spin(n), spawn spin(n), spawn spin(n), spawn spin(n)]
+main(5000)`, "error: budget exceeded: step limit of 50000 exceeded"},
+		{`proc sq(x:int):int -> x * x
+len(range(20000) ||> sq)`, "error: budget exceeded: step limit of 50000 exceeded"},
+	}
+	for _, tt := range tests {
+		for _, b := range backends {
+			got := runScript(t, tt.source, EngineOptions{UseVM: b.useVM, MaxSteps: 50000}, b.optimize)
+			if got != tt.want {
+				t.Errorf("%s: %s:\ngot  %q\nwant %q", b.name, tt.source, got, tt.want)
+			}
+		}
+	}
+}
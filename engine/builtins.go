@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"duet/object"
+)
+
+func newBuiltins() map[string]*object.BuiltinObject {
+	builtins := map[string]*object.BuiltinObject{
+		"type": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got=%d, want=1", len(args))
+				} else {
+					return &object.StringObject{Value: string(args[0].Type())}
+				}
+			},
+		},
+	}
+
+	for name, builtin := range newIOBuiltins() {
+		builtins[name] = builtin
+	}
+
+	for name, builtin := range newListBuiltins() {
+		builtins[name] = builtin
+	}
+
+	for name, builtin := range newMapBuiltins() {
+		builtins[name] = builtin
+	}
+
+	for name, builtin := range newStringBuiltins() {
+		builtins[name] = builtin
+	}
+
+	for name, builtin := range newTypeBuiltins() {
+		builtins[name] = builtin
+	}
+
+	for name, builtin := range newMathBuiltins() {
+		builtins[name] = builtin
+	}
+
+	for name, builtin := range newJSONBuiltins() {
+		builtins[name] = builtin
+	}
+
+	for name, builtin := range newCSVBuiltins() {
+		builtins[name] = builtin
+	}
+
+	for name, builtin := range newStreamBuiltins() {
+		builtins[name] = builtin
+	}
+
+	for name, builtin := range newFlowBuiltins() {
+		builtins[name] = builtin
+	}
+
+	for name, builtin := range newChanBuiltins() {
+		builtins[name] = builtin
+	}
+
+	for name, builtin := range newParallelBuiltins() {
+		builtins[name] = builtin
+	}
+
+	return builtins
+}
+
+var builtins map[string]*object.BuiltinObject
+
+// builtins is filled in init rather than in its declaration because some builtins
+// (pmap) inspect the functions they are given, which in turn looks builtins up.
+func init() {
+	builtins = newBuiltins()
+}
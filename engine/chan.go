@@ -0,0 +1,379 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"duet/ast"
+	"duet/object"
+	"duet/token"
+)
+
+// ChanObject is a channel between goroutines of a Duet program. Channels are
+// implemented on top of the scheduler rather than Go channels so that the
+// scheduler knows which goroutines are waiting and can report a deadlock as an
+// error instead of hanging.
+type ChanObject struct {
+	sched  *scheduler
+	size   int
+	buffer []object.MemoryObject
+	closed bool
+	recvq  []pendingOp
+	sendq  []pendingOp
+}
+
+func (c *ChanObject) Type() object.MemoryObjectType { return object.CHAN_OBJ }
+func (c *ChanObject) Inspect() string               { return "chan" }
+
+// waiter is a goroutine blocked in recv, send or select.
+type waiter struct {
+	fired bool
+	index int                 // the select case that completed
+	value object.MemoryObject // the received value, or an error for a send on a closed channel
+	wake  chan struct{}
+}
+
+// pendingOp is one operation of a waiter queued on a channel.
+type pendingOp struct {
+	w     *waiter
+	index int
+	value object.MemoryObject // the value to send
+}
+
+// selectCase is one channel operation offered to scheduler.choose.
+type selectCase struct {
+	ch    *ChanObject
+	send  bool
+	value object.MemoryObject
+}
+
+// scheduler coordinates the goroutines of one Run: the main program, spawned
+// cons calls and parallel pipeline stages. A single lock guards every channel.
+type scheduler struct {
+	mu      sync.Mutex
+	running int // goroutines that are not waiting on a channel
+	waiting int // goroutines that are
+	spawned sync.WaitGroup
+	failure object.MemoryObject // first error of a spawned cons, or a deadlock
+	stop    chan struct{}       // closed when failure is set
+	ctx     context.Context
+	steps   atomic.Int64 // steps flushed by the engines of the Run, see ExcutionEngine.step
+}
+
+func newScheduler(ctx context.Context) *scheduler {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &scheduler{running: 1, stop: make(chan struct{}), ctx: ctx}
+}
+
+var deadlockError = &object.ErrorObject{Message: "deadlock: every goroutine is waiting on a channel"}
+
+// closedChannel is what recv returns once a channel is closed and drained.
+var closedChannel = &object.FailObject{Message: "channel closed"}
+
+// fail records the first failure and wakes every waiting goroutine with it.
+// The caller holds s.mu.
+func (s *scheduler) fail(err object.MemoryObject) {
+	if s.failure == nil {
+		s.failure = err
+		close(s.stop)
+	}
+}
+
+// enter and leave count goroutines that start and finish running Duet code.
+func (s *scheduler) enter() {
+	s.mu.Lock()
+	s.running++
+	s.mu.Unlock()
+}
+
+func (s *scheduler) leave() {
+	s.mu.Lock()
+	s.running--
+	if s.running == 0 && s.waiting > 0 {
+		s.fail(deadlockError)
+	}
+	s.mu.Unlock()
+}
+
+// wait blocks until every spawned goroutine has finished and returns the first
+// failure among them, if any. The caller is not running while it waits, so
+// spawned goroutines that can never finish are reported as a deadlock.
+func (s *scheduler) wait() object.MemoryObject {
+	s.leave()
+	s.spawned.Wait()
+	s.enter()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.failure
+}
+
+// fire completes the operation op of a waiting goroutine. The caller holds s.mu.
+func (s *scheduler) fire(op pendingOp, value object.MemoryObject) {
+	op.w.fired = true
+	op.w.index = op.index
+	op.w.value = value
+	s.running++
+	s.waiting--
+	op.w.wake <- struct{}{}
+}
+
+// pop removes the first operation in q whose waiter has not fired yet.
+func pop(q *[]pendingOp) (pendingOp, bool) {
+	for len(*q) > 0 {
+		op := (*q)[0]
+		*q = (*q)[1:]
+		if !op.w.fired {
+			return op, true
+		}
+	}
+	return pendingOp{}, false
+}
+
+// choose performs the first ready operation among cases, in order, and returns its
+// index and, for a receive, the value. If none is ready it returns -1 when block is
+// false, and otherwise waits until another goroutine completes one of them.
+func (s *scheduler) choose(cases []selectCase, block bool) (int, object.MemoryObject) {
+	s.mu.Lock()
+	if s.failure != nil {
+		defer s.mu.Unlock()
+		return 0, s.failure
+	}
+	for i, c := range cases {
+		if value, ok := s.try(c); ok {
+			s.mu.Unlock()
+			return i, value
+		}
+	}
+	if !block {
+		s.mu.Unlock()
+		return -1, nil
+	}
+
+	w := &waiter{wake: make(chan struct{}, 1)}
+	for i, c := range cases {
+		op := pendingOp{w: w, index: i, value: c.value}
+		if c.send {
+			c.ch.sendq = append(c.ch.sendq, op)
+		} else {
+			c.ch.recvq = append(c.ch.recvq, op)
+		}
+	}
+	s.running--
+	s.waiting++
+	if s.running == 0 {
+		s.fail(deadlockError)
+	}
+	s.mu.Unlock()
+
+	var err object.MemoryObject
+	select {
+	case <-w.wake:
+		return w.index, w.value
+	case <-s.stop:
+	case <-s.ctx.Done():
+		err = contextError(s.ctx)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if w.fired {
+		// Completed while we were being stopped; the operation has happened.
+		<-w.wake
+		return w.index, w.value
+	}
+	w.fired = true
+	s.running++
+	s.waiting--
+	if err == nil {
+		err = s.failure
+	}
+	return 0, err
+}
+
+// try performs c if it can proceed without waiting. The caller holds s.mu.
+func (s *scheduler) try(c selectCase) (object.MemoryObject, bool) {
+	ch := c.ch
+	if c.send {
+		if ch.closed {
+			return newError("send on closed channel"), true
+		}
+		if op, ok := pop(&ch.recvq); ok {
+			s.fire(op, c.value)
+			return object.Nil, true
+		}
+		if len(ch.buffer) < ch.size {
+			ch.buffer = append(ch.buffer, c.value)
+			return object.Nil, true
+		}
+		return nil, false
+	}
+
+	if len(ch.buffer) > 0 {
+		value := ch.buffer[0]
+		ch.buffer = ch.buffer[1:]
+		if op, ok := pop(&ch.sendq); ok {
+			ch.buffer = append(ch.buffer, op.value)
+			s.fire(op, object.Nil)
+		}
+		return value, true
+	}
+	if op, ok := pop(&ch.sendq); ok {
+		s.fire(op, object.Nil)
+		return op.value, true
+	}
+	if ch.closed {
+		return closedChannel, true
+	}
+	return nil, false
+}
+
+// close closes ch, waking its receivers with a FAIL and its senders with an error.
+func (s *scheduler) close(ch *ChanObject) object.MemoryObject {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ch.closed {
+		return newError("close of closed channel")
+	}
+	ch.closed = true
+	for op, ok := pop(&ch.recvq); ok; op, ok = pop(&ch.recvq) {
+		s.fire(op, closedChannel)
+	}
+	for op, ok := pop(&ch.sendq); ok; op, ok = pop(&ch.sendq) {
+		s.fire(op, newError("send on closed channel"))
+	}
+	return object.Nil
+}
+
+// NewChan creates a channel of this scheduler with room for size buffered values.
+func (s *scheduler) NewChan(size int) object.MemoryObject {
+	return &ChanObject{sched: s, size: size}
+}
+
+// scheduler returns the scheduler of the current Run, creating one for engines
+// that evaluate code without Run.
+func (e *ExcutionEngine) scheduler() *scheduler {
+	if e.sched == nil {
+		e.sched = newScheduler(e.ctx)
+	}
+	return e.sched
+}
+
+// spawn calls the cons fn with args on a new goroutine. The first error it returns
+// wakes every goroutine waiting on a channel and is reported at the end of Run.
+func (e *ExcutionEngine) spawn(fn object.MemoryObject, args []object.MemoryObject) object.MemoryObject {
+	function, ok := fn.(*object.FunctionObject)
+	if !ok || function.Token.Type != token.CONS {
+		return newError("spawn needs a cons, got %s", fn.Inspect())
+	}
+	if err := checkArguments(function, args); err != nil {
+		return err
+	}
+
+	s := e.scheduler()
+	f := e.fork(s.ctx)
+	s.enter()
+	s.spawned.Add(1)
+	go func() {
+		defer s.spawned.Done()
+		defer s.leave()
+		defer f.flushSteps()
+		if result := f.caller()(function, args...); isError(result) {
+			s.mu.Lock()
+			s.fail(result)
+			s.mu.Unlock()
+		}
+	}()
+	return object.Nil
+}
+
+// channelOf returns the channel argument of a channel builtin.
+func channelOf(name string, arg object.MemoryObject) (*ChanObject, *object.ErrorObject) {
+	ch, ok := arg.(*ChanObject)
+	if !ok {
+		return nil, newError("first argument to `%s` must be CHAN, got %s", name, arg.Type())
+	}
+	return ch, nil
+}
+
+// selectCases evaluates the operands of a select expression into channel operations.
+func selectCases(cases []*ast.SelectCase, operands [][]object.MemoryObject) ([]selectCase, *object.ErrorObject) {
+	ops := make([]selectCase, len(cases))
+	for i, c := range cases {
+		name := c.Call.Function.(*ast.Identifier).Value
+		ch, err := channelOf(name, operands[i][0])
+		if err != nil {
+			return nil, err
+		}
+		ops[i] = selectCase{ch: ch, send: c.IsSend()}
+		if ops[i].send {
+			ops[i].value = operands[i][1]
+		}
+	}
+	return ops, nil
+}
+
+func newChanBuiltins() map[string]*object.BuiltinObject {
+	return map[string]*object.BuiltinObject{
+		"chan": {
+			Blocking: func(s object.Scheduler, args ...object.MemoryObject) object.MemoryObject {
+				size := 0
+				switch len(args) {
+				case 0:
+				case 1:
+					n, ok := args[0].(*object.IntegerObject)
+					if !ok || n.Value < 0 {
+						return newError("argument to `chan` must be a non-negative INTEGER, got %s", args[0].Inspect())
+					}
+					size = int(n.Value)
+				default:
+					return newError("wrong number of arguments. got=%d, want=0 or 1", len(args))
+				}
+				return s.NewChan(size)
+			},
+		},
+		"send": {
+			Effectful: true,
+			Blocking: func(s object.Scheduler, args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 2 {
+					return newError("wrong number of arguments. got=%d, want=2", len(args))
+				}
+				ch, err := channelOf("send", args[0])
+				if err != nil {
+					return err
+				}
+				_, result := ch.sched.choose([]selectCase{{ch: ch, send: true, value: args[1]}}, true)
+				return result
+			},
+		},
+		"recv": {
+			Effectful: true,
+			Blocking: func(s object.Scheduler, args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got=%d, want=1", len(args))
+				}
+				ch, err := channelOf("recv", args[0])
+				if err != nil {
+					return err
+				}
+				_, result := ch.sched.choose([]selectCase{{ch: ch}}, true)
+				return result
+			},
+		},
+		"close": {
+			Effectful: true,
+			Blocking: func(s object.Scheduler, args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got=%d, want=1", len(args))
+				}
+				ch, err := channelOf("close", args[0])
+				if err != nil {
+					return err
+				}
+				return ch.sched.close(ch)
+			},
+		},
+	}
+}
@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"encoding/binary"
+)
+
+// Instructions is a sequence of encoded bytecode instructions.
+type Instructions []byte
+
+// Opcode identifies a single VM instruction.
+type Opcode byte
+
+const (
+	OpConstant Opcode = iota // push constants[operand]
+	OpNoValue                // push the "no value" result of a function definition
+	OpPop
+	OpSwap
+	OpGetLocal       // push locals[operand]
+	OpGetGlobal      // push the global or builtin named constants[operand]
+	OpDefineFunction // bind the function described by constants[operand] in global memory
+	OpFail           // push a new FAIL with message constants[operand]
+	OpBang
+	OpMinus
+	OpInfix // apply infixOperators[operand] to the two topmost values
+	OpIndex
+	OpJump
+	OpJumpIfFalse
+	OpList // build a list from the topmost operand values
+	OpMap  // build a map from the topmost 2*operand values
+	OpCall // call with operand arguments
+	OpTailCall
+	OpPipeCall     // call a pipeline stage with operand[0] arguments (the first is the input); operand[1] marks a tail call
+	OpParallelPipe // run the `||>` stages described by the topmost 2*operand values over the value below them
+	OpPipeSource   // invoke a zero-argument function on top of the stack, as `|>` does
+	OpReturn
+	OpAccumulator // push an empty list (operand 0) or map (operand 1) accumulator
+	OpAccumulate  // append the top value to the accumulator operand iterators below it
+	OpAccumulatePair
+	OpFinish    // turn the accumulator on top of the stack into a list or map
+	OpIterStart // replace the collection on top of the stack with an iterator
+	OpIterNext  // bind the next key/value to locals, or jump when exhausted
+	OpYield     // send the top value to the running generator's stream
+	OpSpawn     // run the cons below the topmost operand arguments on a new goroutine
+	OpSelect    // wait for one of the cases of the selectInfo constants[operand] and jump to its body
+	OpImport    // run the module of the import statement constants[operand] if needed and bind it in global memory
+	OpMember    // replace the module on top of the stack with its function named constants[operand]
+)
+
+// noSlot marks an absent local slot operand (e.g. a for generator without a key variable).
+const noSlot = 0xFFFF
+
+// operandWidths lists the byte width of each operand of an opcode.
+var operandWidths = map[Opcode][]int{
+	OpConstant:       {2},
+	OpGetLocal:       {2},
+	OpGetGlobal:      {2},
+	OpDefineFunction: {2},
+	OpFail:           {2},
+	OpInfix:          {1},
+	OpJump:           {2},
+	OpJumpIfFalse:    {2},
+	OpList:           {2},
+	OpMap:            {2},
+	OpCall:           {1},
+	OpTailCall:       {1},
+	OpPipeCall:       {1, 1},
+	OpParallelPipe:   {1},
+	OpAccumulator:    {1},
+	OpAccumulate:     {1},
+	OpAccumulatePair: {1},
+	OpIterNext:       {2, 2, 2},
+	OpSpawn:          {1},
+	OpSelect:         {2},
+	OpImport:         {2},
+	OpMember:         {2},
+}
+
+// infixOperators maps OpInfix operands to the operators understood by evalInfixExpression.
+var infixOperators = []string{"+", "-", "*", "/", "%", "<", ">", "<=", ">=", "==", "!="}
+
+// makeInstruction encodes an opcode and its operands.
+func makeInstruction(op Opcode, operands ...int) []byte {
+	widths := operandWidths[op]
+	length := 1
+	for _, w := range widths {
+		length += w
+	}
+
+	instruction := make([]byte, length)
+	instruction[0] = byte(op)
+	offset := 1
+	for i, o := range operands {
+		switch widths[i] {
+		case 1:
+			instruction[offset] = byte(o)
+		case 2:
+			binary.BigEndian.PutUint16(instruction[offset:], uint16(o))
+		}
+		offset += widths[i]
+	}
+	return instruction
+}
+
+func readUint16(ins Instructions, offset int) int {
+	return int(binary.BigEndian.Uint16(ins[offset:]))
+}
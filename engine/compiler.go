@@ -0,0 +1,623 @@
+package engine
+
+import (
+	"fmt"
+
+	"duet/ast"
+	"duet/object"
+)
+
+// CompiledFunction is the bytecode of a function body or of a whole program.
+// Parameters occupy the first local slots, followed by the variables of for generators.
+type CompiledFunction struct {
+	Instructions Instructions
+	Constants    []any
+	NumLocals    int
+}
+
+// functionDefinition is the constant operand of OpDefineFunction.
+type functionDefinition struct {
+	Statement *ast.FunctionStatement
+	Code      *CompiledFunction
+}
+
+// blockScope maps the variables visible in one for generator (or a function's parameters) to slots.
+type blockScope struct {
+	slots map[string]int
+	outer *blockScope
+}
+
+// Compiler translates an AST into bytecode for the VM.
+// Since functions can only be defined at the top level, a name is either a local
+// of the function being compiled or a global looked up by name at run time.
+type Compiler struct {
+	instructions Instructions
+	constants    []any
+	block        *blockScope
+	numLocals    int
+	err          error // the first operand too large for its encoding, reported by finish
+}
+
+// Compile compiles a whole program. The result leaves the value of the last statement on return.
+func Compile(program *ast.Program) (*CompiledFunction, error) {
+	c := &Compiler{block: &blockScope{slots: map[string]int{}}}
+
+	if len(program.Statements) == 0 {
+		c.emit(OpNoValue)
+	}
+	for i, stmt := range program.Statements {
+		if err := c.compileStatement(stmt); err != nil {
+			return nil, err
+		}
+		if i < len(program.Statements)-1 {
+			c.emit(OpPop)
+		}
+	}
+	c.emit(OpReturn)
+
+	return c.finish()
+}
+
+// compileFunction compiles a function body with its parameters bound to the first slots.
+func compileFunction(parameters []*ast.Parameter, body ast.Expression) (*CompiledFunction, error) {
+	c := &Compiler{block: &blockScope{slots: map[string]int{}}}
+	for _, param := range parameters {
+		c.define(param.Name.Value)
+	}
+	// The value of a generator body is thrown away; only what it yields matters.
+	compileBody := c.compile
+	if isGenerator(body) {
+		compileBody = func(node ast.Expression, _ bool) error { return c.compileDiscard(node) }
+	}
+	if err := compileBody(body, true); err != nil {
+		return nil, err
+	}
+	c.emit(OpReturn)
+	return c.finish()
+}
+
+func (c *Compiler) finish() (*CompiledFunction, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &CompiledFunction{Instructions: c.instructions, Constants: c.constants, NumLocals: c.numLocals}, nil
+}
+
+func (c *Compiler) compileStatement(stmt ast.Statement) error {
+	switch stmt := stmt.(type) {
+	case *ast.ExpressionStatement:
+		return c.compile(stmt.Expression, false)
+	case *ast.FunctionStatement:
+		code, err := compileFunction(stmt.Parameters, stmt.Body)
+		if err != nil {
+			return err
+		}
+		c.emit(OpDefineFunction, c.addConstant(&functionDefinition{Statement: stmt, Code: code}))
+		c.emit(OpNoValue)
+		return nil
+	case *ast.ImportStatement:
+		c.emit(OpImport, c.addConstant(stmt))
+		c.emit(OpNoValue)
+		return nil
+	default:
+		return fmt.Errorf("compile error: unsupported statement %T", stmt)
+	}
+}
+
+// compile emits code leaving the value of node on the stack.
+// tail marks the tail position of a function body, where calls become OpTailCall.
+func (c *Compiler) compile(node ast.Expression, tail bool) error {
+	switch node := node.(type) {
+	case *ast.IntegerLiteral:
+		c.emit(OpConstant, c.addConstant(&object.IntegerObject{Value: node.Value}))
+	case *ast.FloatLiteral:
+		c.emit(OpConstant, c.addConstant(&object.FloatObject{Value: node.Value}))
+	case *ast.StringLiteral:
+		c.emit(OpConstant, c.addConstant(&object.StringObject{Value: node.Value}))
+	case *ast.BooleanLiteral:
+		c.emit(OpConstant, c.addConstant(nativeBoolToBooleanObject(node.Value)))
+	case *ast.NilLiteral:
+		c.emit(OpConstant, c.addConstant(object.Nil))
+	case *ast.FailExpression:
+		c.emit(OpFail, c.addConstant(node.Message))
+
+	case *ast.Identifier:
+		if slot, ok := c.resolve(node.Value); ok {
+			c.emit(OpGetLocal, slot)
+		} else {
+			c.emit(OpGetGlobal, c.addConstant(node.Value))
+		}
+
+	case *ast.MemberExpression:
+		c.emit(OpGetGlobal, c.addConstant(node.Module.Value))
+		c.emit(OpMember, c.addConstant(node))
+
+	case *ast.YieldExpression:
+		if err := c.compile(node.Value, false); err != nil {
+			return err
+		}
+		c.emit(OpYield)
+
+	case *ast.SpawnExpression:
+		if err := c.compile(node.Call.Function, false); err != nil {
+			return err
+		}
+		argc, err := c.compileExpressions(node.Call.Arguments)
+		if err != nil {
+			return err
+		}
+		c.emit(OpSpawn, argc)
+
+	case *ast.SelectExpression:
+		return c.compileSelect(node, tail)
+
+	case *ast.PrefixExpression:
+		if err := c.compile(node.Right, false); err != nil {
+			return err
+		}
+		switch node.Operator {
+		case "!":
+			c.emit(OpBang)
+		case "-":
+			c.emit(OpMinus)
+		default:
+			return fmt.Errorf("compile error: unknown operator %s", node.Operator)
+		}
+
+	case *ast.InfixExpression:
+		if node.Operator == "|>" {
+			return c.compilePipeline(node, tail)
+		}
+		if node.Operator == "||>" {
+			return c.compileParallelPipeline(node)
+		}
+		op := -1
+		for i, candidate := range infixOperators {
+			if candidate == node.Operator {
+				op = i
+			}
+		}
+		if op < 0 {
+			return fmt.Errorf("compile error: unknown operator %s", node.Operator)
+		}
+		if err := c.compile(node.Left, false); err != nil {
+			return err
+		}
+		if err := c.compile(node.Right, false); err != nil {
+			return err
+		}
+		c.emit(OpInfix, op)
+
+	case *ast.IfExpression:
+		if err := c.compile(node.Condition, false); err != nil {
+			return err
+		}
+		jumpIfFalse := c.emit(OpJumpIfFalse, 0)
+		if err := c.compile(node.Consequence, tail); err != nil {
+			return err
+		}
+		jump := c.emit(OpJump, 0)
+		c.patchJump(jumpIfFalse)
+		if node.Alternative != nil {
+			if err := c.compile(node.Alternative, tail); err != nil {
+				return err
+			}
+		} else {
+			c.emit(OpConstant, c.addConstant(object.Nil))
+		}
+		c.patchJump(jump)
+
+	case *ast.MatchExpression:
+		// The subject is evaluated only for its errors; cases are plain conditions.
+		if err := c.compile(node.Subject, false); err != nil {
+			return err
+		}
+		c.emit(OpPop)
+		var jumps []int
+		for _, mc := range node.Cases {
+			if err := c.compile(mc.Condition, false); err != nil {
+				return err
+			}
+			next := c.emit(OpJumpIfFalse, 0)
+			if err := c.compile(mc.Consequence, tail); err != nil {
+				return err
+			}
+			jumps = append(jumps, c.emit(OpJump, 0))
+			c.patchJump(next)
+		}
+		if node.Default != nil {
+			if err := c.compile(node.Default, tail); err != nil {
+				return err
+			}
+		} else {
+			c.emit(OpConstant, c.addConstant(object.Nil))
+		}
+		for _, jump := range jumps {
+			c.patchJump(jump)
+		}
+
+	case *ast.ForExpression:
+		return c.compileFor(node)
+
+	case *ast.CallExpression:
+		if err := c.compile(node.Function, false); err != nil {
+			return err
+		}
+		argc, err := c.compileExpressions(node.Arguments)
+		if err != nil {
+			return err
+		}
+		if tail {
+			c.emit(OpTailCall, argc)
+		} else {
+			c.emit(OpCall, argc)
+		}
+
+	case *ast.ListLiteral:
+		count, err := c.compileExpressions(node.Elements)
+		if err != nil {
+			return err
+		}
+		c.emit(OpList, count)
+
+	case *ast.MapLiteral:
+		for key, value := range node.Pairs {
+			if err := c.compile(key, false); err != nil {
+				return err
+			}
+			if err := c.compile(value, false); err != nil {
+				return err
+			}
+		}
+		c.emit(OpMap, len(node.Pairs))
+
+	case *ast.IndexExpression:
+		if err := c.compile(node.Left, false); err != nil {
+			return err
+		}
+		if err := c.compile(node.Index, false); err != nil {
+			return err
+		}
+		c.emit(OpIndex)
+
+	default:
+		return fmt.Errorf("compile error: unsupported expression %T", node)
+	}
+	return nil
+}
+
+// compileExpressions compiles call arguments or list elements and returns how many values it pushed.
+// Like evalExpressions, a `fail` literal replaces every value evaluated before it.
+func (c *Compiler) compileExpressions(exps []ast.Expression) (int, error) {
+	for i, exp := range exps {
+		if _, ok := exp.(*ast.FailExpression); ok {
+			for j := 0; j < i; j++ {
+				c.emit(OpPop)
+			}
+			return 1, c.compile(exp, false)
+		}
+		if err := c.compile(exp, false); err != nil {
+			return 0, err
+		}
+	}
+	return len(exps), nil
+}
+
+// compilePipeline keeps the evaluation order of evalPipeline: the left side first,
+// then the stage function, then the stage's own arguments.
+func (c *Compiler) compilePipeline(node *ast.InfixExpression, tail bool) error {
+	if err := c.compile(node.Left, false); err != nil {
+		return err
+	}
+	c.emit(OpPipeSource)
+
+	argc := 1
+	if call, ok := node.Right.(*ast.CallExpression); ok {
+		if err := c.compile(call.Function, false); err != nil {
+			return err
+		}
+		c.emit(OpSwap)
+		n, err := c.compileExpressions(call.Arguments)
+		if err != nil {
+			return err
+		}
+		argc += n
+	} else {
+		if err := c.compile(node.Right, false); err != nil {
+			return err
+		}
+		c.emit(OpSwap)
+	}
+
+	isTail := 0
+	if tail {
+		isTail = 1
+	}
+	c.emit(OpPipeCall, argc, isTail)
+	return nil
+}
+
+// compileParallelPipeline pushes the source of a `||>` chain followed by a function
+// and a list of extra arguments per stage; OpParallelPipe runs them.
+func (c *Compiler) compileParallelPipeline(node *ast.InfixExpression) error {
+	source, stages := parallelStages(node)
+	if err := c.compile(source, false); err != nil {
+		return err
+	}
+	c.emit(OpPipeSource)
+	for _, stage := range stages {
+		call, ok := stage.(*ast.CallExpression)
+		if !ok {
+			if err := c.compile(stage, false); err != nil {
+				return err
+			}
+			c.emit(OpList, 0)
+			continue
+		}
+		if err := c.compile(call.Function, false); err != nil {
+			return err
+		}
+		argc, err := c.compileExpressions(call.Arguments)
+		if err != nil {
+			return err
+		}
+		c.emit(OpList, argc)
+	}
+	c.emit(OpParallelPipe, len(stages))
+	return nil
+}
+
+// selectInfo is the constant operand of OpSelect. Targets holds the address of the
+// body of each case and Slots the local that receives its value (or noSlot).
+type selectInfo struct {
+	Cases      []*ast.SelectCase
+	Slots      []int
+	Targets    []int
+	HasDefault bool
+	Default    int
+}
+
+// compileSelect pushes the arguments of every case, then OpSelect jumps to the body
+// of the case that proceeds, or to the default.
+func (c *Compiler) compileSelect(node *ast.SelectExpression, tail bool) error {
+	info := &selectInfo{
+		Cases:      node.Cases,
+		Slots:      make([]int, len(node.Cases)),
+		Targets:    make([]int, len(node.Cases)),
+		HasDefault: node.Default != nil,
+	}
+	for _, sc := range node.Cases {
+		if _, err := c.compileExpressions(sc.Call.Arguments); err != nil {
+			return err
+		}
+	}
+	c.emit(OpSelect, c.addConstant(info))
+
+	var jumps []int
+	for i, sc := range node.Cases {
+		info.Targets[i] = len(c.instructions)
+		info.Slots[i] = noSlot
+		if sc.Variable != nil {
+			c.block = &blockScope{slots: map[string]int{}, outer: c.block}
+			info.Slots[i] = c.define(sc.Variable.Value)
+		}
+		err := c.compile(sc.Body, tail)
+		if sc.Variable != nil {
+			c.block = c.block.outer
+		}
+		if err != nil {
+			return err
+		}
+		jumps = append(jumps, c.emit(OpJump, 0))
+	}
+	info.Default = len(c.instructions)
+	if node.Default != nil {
+		if err := c.compile(node.Default, tail); err != nil {
+			return err
+		}
+	} else {
+		c.emit(OpConstant, c.addConstant(object.Nil))
+	}
+	for _, jump := range jumps {
+		c.patchJump(jump)
+	}
+	return nil
+}
+
+func (c *Compiler) compileFor(fe *ast.ForExpression) error {
+	kind := 0
+	if fe.MapKey != nil {
+		kind = 1
+	}
+	c.emit(OpAccumulator, kind)
+	err := c.compileGenerators(fe.Generators, 0, func(depth int) error {
+		if fe.MapKey != nil {
+			if err := c.compile(fe.MapKey, false); err != nil {
+				return err
+			}
+			if err := c.compile(fe.Body, false); err != nil {
+				return err
+			}
+			c.emit(OpAccumulatePair, depth)
+			return nil
+		}
+		if err := c.compile(fe.Body, false); err != nil {
+			return err
+		}
+		c.emit(OpAccumulate, depth)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	c.emit(OpFinish)
+	return nil
+}
+
+// compileDiscard compiles node for its effects only, like evalDiscard: a for in
+// tail position builds no result. It leaves a single (meaningless) value on the stack.
+func (c *Compiler) compileDiscard(node ast.Expression) error {
+	switch node := node.(type) {
+	case *ast.ForExpression:
+		err := c.compileGenerators(node.Generators, 0, func(int) error {
+			if node.MapKey != nil {
+				if err := c.compile(node.MapKey, false); err != nil {
+					return err
+				}
+				c.emit(OpPop)
+			}
+			if err := c.compileDiscard(node.Body); err != nil {
+				return err
+			}
+			c.emit(OpPop)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		c.emit(OpConstant, c.addConstant(object.Nil))
+		return nil
+
+	case *ast.IfExpression:
+		if err := c.compile(node.Condition, false); err != nil {
+			return err
+		}
+		jumpIfFalse := c.emit(OpJumpIfFalse, 0)
+		if err := c.compileDiscard(node.Consequence); err != nil {
+			return err
+		}
+		jump := c.emit(OpJump, 0)
+		c.patchJump(jumpIfFalse)
+		if node.Alternative != nil {
+			if err := c.compileDiscard(node.Alternative); err != nil {
+				return err
+			}
+		} else {
+			c.emit(OpConstant, c.addConstant(object.Nil))
+		}
+		c.patchJump(jump)
+		return nil
+
+	case *ast.MatchExpression:
+		if err := c.compile(node.Subject, false); err != nil {
+			return err
+		}
+		c.emit(OpPop)
+		var jumps []int
+		for _, mc := range node.Cases {
+			if err := c.compile(mc.Condition, false); err != nil {
+				return err
+			}
+			next := c.emit(OpJumpIfFalse, 0)
+			if err := c.compileDiscard(mc.Consequence); err != nil {
+				return err
+			}
+			jumps = append(jumps, c.emit(OpJump, 0))
+			c.patchJump(next)
+		}
+		if node.Default != nil {
+			if err := c.compileDiscard(node.Default); err != nil {
+				return err
+			}
+		} else {
+			c.emit(OpConstant, c.addConstant(object.Nil))
+		}
+		for _, jump := range jumps {
+			c.patchJump(jump)
+		}
+		return nil
+	}
+	return c.compile(node, false)
+}
+
+// compileGenerators emits one nested loop per generator and calls leaf for the
+// innermost body. While it runs, one iterator per generator sits on the stack (above
+// the accumulator of a for expression), and depth tells leaf how many.
+func (c *Compiler) compileGenerators(generators []*ast.ForGenerator, depth int, leaf func(depth int) error) error {
+	if len(generators) == 0 {
+		return leaf(depth)
+	}
+
+	gen := generators[0]
+	if err := c.compile(gen.Collection, false); err != nil {
+		return err
+	}
+	c.emit(OpIterStart)
+
+	c.block = &blockScope{slots: map[string]int{}, outer: c.block}
+	defer func() { c.block = c.block.outer }()
+
+	keySlot := noSlot
+	if gen.Key != nil {
+		keySlot = c.define(gen.Key.Value)
+	}
+	varSlot := c.define(gen.Variable.Value)
+
+	loop := len(c.instructions)
+	next := c.emit(OpIterNext, keySlot, varSlot, 0)
+	if gen.Condition != nil {
+		if err := c.compile(gen.Condition, false); err != nil {
+			return err
+		}
+		c.emit(OpJumpIfFalse, loop)
+	}
+	if err := c.compileGenerators(generators[1:], depth+1, leaf); err != nil {
+		return err
+	}
+	c.emit(OpJump, loop)
+
+	// OpIterNext jumps here when the iterator is exhausted.
+	c.replaceOperand(next, 5, len(c.instructions))
+	c.emit(OpPop)
+	return nil
+}
+
+func (c *Compiler) define(name string) int {
+	slot := c.numLocals
+	c.block.slots[name] = slot
+	c.numLocals++
+	return slot
+}
+
+func (c *Compiler) resolve(name string) (int, bool) {
+	for b := c.block; b != nil; b = b.outer {
+		if slot, ok := b.slots[name]; ok {
+			return slot, true
+		}
+	}
+	return 0, false
+}
+
+func (c *Compiler) addConstant(value any) int {
+	c.constants = append(c.constants, value)
+	return len(c.constants) - 1
+}
+
+// emit appends an instruction and returns its position.
+func (c *Compiler) emit(op Opcode, operands ...int) int {
+	for i, o := range operands {
+		c.checkOperand(o, operandWidths[op][i])
+	}
+	pos := len(c.instructions)
+	c.instructions = append(c.instructions, makeInstruction(op, operands...)...)
+	return pos
+}
+
+// patchJump points the jump instruction at pos to the current end of the code.
+func (c *Compiler) patchJump(pos int) {
+	c.replaceOperand(pos, 1, len(c.instructions))
+}
+
+func (c *Compiler) replaceOperand(pos, offset, value int) {
+	c.checkOperand(value, 2)
+	c.instructions[pos+offset] = byte(value >> 8)
+	c.instructions[pos+offset+1] = byte(value)
+}
+
+// checkOperand records an error if o does not fit in width bytes, e.g. for a list
+// literal with more than 65535 elements or a call with more than 255 arguments.
+func (c *Compiler) checkOperand(o, width int) {
+	if c.err == nil && (o < 0 || o >= 1<<(8*width)) {
+		c.err = fmt.Errorf("compile error: program too large for the VM: %d does not fit in a %d-byte operand", o, width)
+	}
+}
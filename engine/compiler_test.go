@@ -0,0 +1,40 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestOperandLimits(t *testing.T) {
+	numbers := func(n int) string {
+		parts := make([]string, n)
+		for i := range parts {
+			parts[i] = fmt.Sprint(i)
+		}
+		return strings.Join(parts, ", ")
+	}
+	tests := []struct {
+		name     string
+		source   string
+		wantVM   string
+		wantTree string // the tree walker has no such limits
+	}{
+		{"largest list", "len([" + numbers(0xFFFF) + "])", "=> 65535", "=> 65535"},
+		{"list too large", "len([" + numbers(70000) + "])",
+			"error: compile error: program too large for the VM: 65536 does not fit in a 2-byte operand", "=> 70000"},
+		{"too many arguments", "len(" + numbers(256) + ")",
+			"error: compile error: program too large for the VM: 256 does not fit in a 1-byte operand",
+			"=> wrong number of arguments. got=256, want=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runScript(t, tt.source, EngineOptions{UseVM: true}, false); got != tt.wantVM {
+				t.Errorf("vm:\ngot  %.200q\nwant %q", got, tt.wantVM)
+			}
+			if got := runScript(t, tt.source, EngineOptions{}, false); got != tt.wantTree {
+				t.Errorf("tree:\ngot  %.200q\nwant %q", got, tt.wantTree)
+			}
+		})
+	}
+}
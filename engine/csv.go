@@ -0,0 +1,386 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"duet/object"
+)
+
+// csvOptions are the options map of csv_read and csv_write.
+type csvOptions struct {
+	delimiter  rune
+	header     bool     // the first row names the columns
+	infer      bool     // csv_read: numeric fields become int or float
+	lazyQuotes bool     // csv_read: accept stray quotes as ordinary characters
+	stream     bool     // csv_read: return a stream instead of a list
+	quoteAll   bool     // csv_write: quote every field, not only those that need it
+	columns    []string // csv_write: the columns of map rows, in order
+}
+
+// csvOptionNames lists the options each builtin accepts.
+var csvOptionNames = map[string][]string{
+	"csv_read":  {"delimiter", "header", "infer", "lazy_quotes", "stream"},
+	"csv_write": {"delimiter", "header", "quote_all", "columns"},
+}
+
+func parseCSVOptions(name string, arg object.MemoryObject) (csvOptions, *object.ErrorObject) {
+	options := csvOptions{delimiter: ',', header: true}
+	m, ok := arg.(*object.MapObject)
+	if !ok {
+		return options, newError("options of `%s` must be MAP, got %s", name, arg.Type())
+	}
+	for _, pair := range m.SortedPairs() {
+		key, ok := pair.Key.(*object.StringObject)
+		if !ok || !slices.Contains(csvOptionNames[name], key.Value) {
+			return options, newError("unknown option %s to `%s`, want one of %s", pair.Key.Inspect(), name, strings.Join(csvOptionNames[name], ", "))
+		}
+		var err *object.ErrorObject
+		switch key.Value {
+		case "delimiter":
+			s, ok := pair.Value.(*object.StringObject)
+			if !ok {
+				return options, newError("option delimiter of `%s` must be STRING, got %s", name, pair.Value.Type())
+			}
+			r, size := utf8.DecodeRuneInString(s.Value)
+			if size == 0 || size != len(s.Value) || r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+				err = newError("option delimiter of `%s` must be one character other than a quote or line break, got %q", name, s.Value)
+			}
+			options.delimiter = r
+		case "header":
+			options.header, err = csvBoolOption(name, key.Value, pair.Value)
+		case "infer":
+			options.infer, err = csvBoolOption(name, key.Value, pair.Value)
+		case "lazy_quotes":
+			options.lazyQuotes, err = csvBoolOption(name, key.Value, pair.Value)
+		case "stream":
+			options.stream, err = csvBoolOption(name, key.Value, pair.Value)
+		case "quote_all":
+			options.quoteAll, err = csvBoolOption(name, key.Value, pair.Value)
+		case "columns":
+			options.columns, err = csvColumnsOption(name, pair.Value)
+		}
+		if err != nil {
+			return options, err
+		}
+	}
+	return options, nil
+}
+
+func csvBoolOption(name, option string, value object.MemoryObject) (bool, *object.ErrorObject) {
+	b, ok := value.(*object.BooleanObject)
+	if !ok {
+		return false, newError("option %s of `%s` must be BOOLEAN, got %s", option, name, value.Type())
+	}
+	return b.Value, nil
+}
+
+func csvColumnsOption(name string, value object.MemoryObject) ([]string, *object.ErrorObject) {
+	list, ok := value.(*object.ListObject)
+	if !ok {
+		return nil, newError("option columns of `%s` must be LIST, got %s", name, value.Type())
+	}
+	columns := []string{}
+	for _, el := range list.All() {
+		s, ok := el.(*object.StringObject)
+		if !ok {
+			return nil, newError("option columns of `%s` must be a list of STRING, got %s", name, el.Type())
+		}
+		columns = append(columns, s.Value)
+	}
+	return columns, nil
+}
+
+func newCSVBuiltins() map[string]*object.BuiltinObject {
+	return map[string]*object.BuiltinObject{
+		"csv_read": {
+			Effectful: true,
+			IO: func(sys *object.IO, args ...object.MemoryObject) object.MemoryObject {
+				if len(args) < 1 || len(args) > 2 {
+					return object.NewFail("wrong number of arguments. got=%d, want=1 or 2", len(args))
+				}
+				path, ok := args[0].(*object.StringObject)
+				if !ok {
+					return object.NewFail("first argument to `csv_read` must be STRING, got %s", args[0].Type())
+				}
+				options := csvOptions{delimiter: ',', header: true}
+				if len(args) == 2 {
+					var err *object.ErrorObject
+					if options, err = parseCSVOptions("csv_read", args[1]); err != nil {
+						return err
+					}
+				}
+				file, err := sys.Open(path.Value)
+				if err != nil {
+					return ioFail("open file", err)
+				}
+				rows := newCSVRows(file, options)
+				if options.stream {
+					return object.NewStream(rows.next, func() { file.Close() })
+				}
+				defer file.Close()
+				var elements []object.MemoryObject
+				for {
+					row, ok := rows.next()
+					if !ok {
+						return object.NewList(elements)
+					}
+					if row.Type() == object.FAIL_OBJ {
+						return row
+					}
+					elements = append(elements, row)
+				}
+			},
+		},
+		"csv_write": {
+			Effectful: true,
+			IO: func(sys *object.IO, args ...object.MemoryObject) object.MemoryObject {
+				if len(args) < 2 || len(args) > 3 {
+					return object.NewFail("wrong number of arguments. got=%d, want=2 or 3", len(args))
+				}
+				path, ok := args[0].(*object.StringObject)
+				if !ok {
+					return object.NewFail("first argument to `csv_write` must be STRING, got %s", args[0].Type())
+				}
+				rows, ok := args[1].(*object.ListObject)
+				if !ok {
+					return object.NewFail("second argument to `csv_write` must be LIST, got %s", args[1].Type())
+				}
+				options := csvOptions{delimiter: ',', header: true}
+				if len(args) == 3 {
+					var err *object.ErrorObject
+					if options, err = parseCSVOptions("csv_write", args[2]); err != nil {
+						return err
+					}
+				}
+				data, err := formatCSV(rows, options)
+				if err != nil {
+					return object.NewFail("could not write CSV: %s", err)
+				}
+				if err := sys.WriteFile(path.Value, data); err != nil {
+					return ioFail("write file", err)
+				}
+				return object.True
+			},
+		},
+	}
+}
+
+// csvRows reads the rows of a CSV file one at a time, as maps keyed by the
+// header or, without one, as lists.
+type csvRows struct {
+	reader  *csv.Reader
+	options csvOptions
+	header  []string
+	done    bool
+}
+
+func newCSVRows(r io.Reader, options csvOptions) *csvRows {
+	reader := csv.NewReader(r)
+	reader.Comma = options.delimiter
+	reader.LazyQuotes = options.lazyQuotes
+	reader.ReuseRecord = true
+	return &csvRows{reader: reader, options: options}
+}
+
+// next returns the next row, or a FAIL for a malformed file after which there
+// are no more rows.
+func (c *csvRows) next() (object.MemoryObject, bool) {
+	if c.done {
+		return nil, false
+	}
+	record, err := c.reader.Read()
+	if err == nil && c.options.header && c.header == nil {
+		if c.header, err = csvHeader(record); err == nil {
+			record, err = c.reader.Read()
+		}
+	}
+	if err != nil {
+		c.done = true
+		if errors.Is(err, io.EOF) {
+			return nil, false
+		}
+		return object.NewFail("could not read CSV: %s", err), true
+	}
+
+	if c.header == nil {
+		elements := make([]object.MemoryObject, len(record))
+		for i, field := range record {
+			elements[i] = c.field(field)
+		}
+		return object.NewList(elements), true
+	}
+	m := object.NewMap()
+	for i, field := range record {
+		key := &object.StringObject{Value: c.header[i]}
+		m = m.Set(key.HashKey(), object.MapPair{Key: key, Value: c.field(field)})
+	}
+	return m, true
+}
+
+func csvHeader(record []string) ([]string, error) {
+	seen := map[string]bool{}
+	for _, name := range record {
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate column %q in header", name)
+		}
+		seen[name] = true
+	}
+	return append([]string(nil), record...), nil
+}
+
+// field converts a field to a value. With the infer option, integers and decimal
+// numbers become INTEGER and FLOAT; everything else stays a STRING.
+func (c *csvRows) field(s string) object.MemoryObject {
+	if c.options.infer && isCSVNumber(s) {
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return &object.IntegerObject{Value: i}
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return &object.FloatObject{Value: f}
+		}
+	}
+	return &object.StringObject{Value: s}
+}
+
+// isCSVNumber reports whether s looks like a number, which keeps ParseFloat from
+// accepting words such as "inf" and "NaN". Numbers with a leading zero, such as
+// codes like "007", are not numbers.
+func isCSVNumber(s string) bool {
+	if t := strings.TrimLeft(s, "+-"); len(t) > 1 && t[0] == '0' && '0' <= t[1] && t[1] <= '9' {
+		return false
+	}
+	digits := false
+	for _, r := range s {
+		switch {
+		case '0' <= r && r <= '9':
+			digits = true
+		case !strings.ContainsRune("+-.eE", r):
+			return false
+		}
+	}
+	return digits
+}
+
+// formatCSV writes rows, which are either all lists or all maps, as CSV. Map rows
+// are written in the order of the columns option or, without it, of all their
+// keys in key order, under a header row unless the header option is false. A
+// list of column names given with list rows is written as their header.
+func formatCSV(rows *object.ListObject, options csvOptions) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = options.delimiter
+	write := w.Write
+	if options.quoteAll {
+		write = func(record []string) error { return writeQuoted(&buf, record, options.delimiter) }
+	}
+
+	columns, maps, err := csvColumns(rows, options)
+	if err != nil {
+		return nil, err
+	}
+	if columns != nil && options.header {
+		if err := write(columns); err != nil {
+			return nil, err
+		}
+	}
+	for i, row := range rows.All() {
+		var record []string
+		if maps {
+			m := row.(*object.MapObject)
+			record = make([]string, len(columns))
+			for j, column := range columns {
+				key := &object.StringObject{Value: column}
+				if pair, ok := m.Get(key.HashKey()); ok {
+					if record[j], err = csvField(pair.Value); err != nil {
+						return nil, fmt.Errorf("row %d, column %s: %w", i+1, column, err)
+					}
+				}
+			}
+		} else {
+			for j, el := range row.(*object.ListObject).All() {
+				field, err := csvField(el)
+				if err != nil {
+					return nil, fmt.Errorf("row %d, field %d: %w", i+1, j+1, err)
+				}
+				record = append(record, field)
+			}
+		}
+		if err := write(record); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// csvColumns returns the columns to write and whether the rows are maps.
+func csvColumns(rows *object.ListObject, options csvOptions) ([]string, bool, error) {
+	lists, maps := 0, 0
+	var keys []string
+	seen := map[string]bool{}
+	for i, row := range rows.All() {
+		switch row := row.(type) {
+		case *object.ListObject:
+			lists++
+		case *object.MapObject:
+			maps++
+			for _, pair := range row.All() {
+				key, ok := pair.Key.(*object.StringObject)
+				if !ok {
+					return nil, false, fmt.Errorf("row %d: map keys must be STRING, got %s", i+1, pair.Key.Type())
+				}
+				if !seen[key.Value] {
+					seen[key.Value] = true
+					keys = append(keys, key.Value)
+				}
+			}
+		default:
+			return nil, false, fmt.Errorf("row %d must be LIST or MAP, got %s", i+1, row.Type())
+		}
+	}
+	if lists > 0 && maps > 0 {
+		return nil, false, errors.New("rows must be all lists or all maps")
+	}
+	if options.columns != nil || maps == 0 {
+		return options.columns, maps > 0, nil
+	}
+	slices.Sort(keys)
+	return keys, true, nil
+}
+
+func csvField(obj object.MemoryObject) (string, error) {
+	switch obj := obj.(type) {
+	case *object.StringObject:
+		return obj.Value, nil
+	case *object.IntegerObject, *object.BooleanObject:
+		return obj.Inspect(), nil
+	case *object.FloatObject:
+		return strconv.FormatFloat(obj.Value, 'f', -1, 64), nil
+	case *object.NilObject:
+		return "", nil
+	}
+	return "", fmt.Errorf("%s cannot be written as a CSV field", obj.Type())
+}
+
+// writeQuoted writes a record with every field quoted, which csv.Writer only
+// does for fields that need it.
+func writeQuoted(buf *bytes.Buffer, record []string, delimiter rune) error {
+	for i, field := range record {
+		if i > 0 {
+			buf.WriteRune(delimiter)
+		}
+		buf.WriteByte('"')
+		buf.WriteString(strings.ReplaceAll(field, `"`, `""`))
+		buf.WriteByte('"')
+	}
+	buf.WriteByte('\n')
+	return nil
+}
@@ -0,0 +1,176 @@
+package engine
+
+import (
+	"fmt"
+
+	"duet/ast"
+	"duet/object"
+	"duet/token"
+)
+
+// EffectChecker enforces the roles of the three function kinds:
+//
+//   - a proc transforms data, so it must not reach an Effectful builtin or a cons,
+//     directly or through a supp that does;
+//   - a cons only consumes data, so its result cannot feed a pipeline.
+//
+// It runs after the Resolver, whose lexical addresses tell locals from globals.
+type EffectChecker struct {
+	globals   *object.Memory
+	functions map[string]*ast.FunctionStatement // functions defined by the program being checked
+	modules   map[string]*Module                // modules imported by the program being checked
+	effects   map[string]string                 // effect reached by each supp, "" if none
+	visiting  map[string]bool
+	errors    []string
+}
+
+// NewEffectChecker creates an EffectChecker. Functions already set in globals
+// (e.g. by earlier REPL lines) are taken into account when the program calls them.
+func NewEffectChecker(globals *object.Memory) *EffectChecker {
+	return &EffectChecker{globals: globals}
+}
+
+func (c *EffectChecker) Errors() []string {
+	return c.errors
+}
+
+// Check reports every proc of program that reaches an effect and every pipeline
+// that takes its input from a cons.
+func (c *EffectChecker) Check(program *ast.Program) {
+	c.functions = map[string]*ast.FunctionStatement{}
+	c.modules = imports(program)
+	c.effects = map[string]string{}
+	c.visiting = map[string]bool{}
+	for _, stmt := range program.Statements {
+		if fs, ok := stmt.(*ast.FunctionStatement); ok {
+			c.functions[fs.Name.Value] = fs
+		}
+	}
+
+	for _, stmt := range program.Statements {
+		if fs, ok := stmt.(*ast.FunctionStatement); ok && fs.Token.Type == token.PROC {
+			if effect := c.effectOf(fs.Body); effect != "" {
+				c.errors = append(c.errors, fmt.Sprintf("effect error: proc %s calls %s", fs.Name.Value, effect))
+			}
+		}
+	}
+
+	ast.Inspect(program, func(node ast.Node) bool {
+		if pipe, ok := node.(*ast.InfixExpression); ok && pipe.IsPipeline() {
+			if name := c.consSource(pipe.Left); name != "" {
+				c.errors = append(c.errors, fmt.Sprintf("effect error: the result of cons %s cannot be used as pipeline input", name))
+			}
+		}
+		return true
+	})
+}
+
+// effectOf describes the first effect body can reach, or returns "" if it has none.
+// Other procs are not followed: each one is checked on its own.
+func (c *EffectChecker) effectOf(body ast.Expression) string {
+	effect := ""
+	ast.Inspect(body, func(node ast.Node) bool {
+		if me, ok := node.(*ast.MemberExpression); ok && effect == "" {
+			if module := moduleNamed(me.Module.Value, c.modules, c.globals); module != nil {
+				effect = module.effect(me.Module.Value, me.Member.Value)
+			}
+			return false
+		}
+		ident, ok := node.(*ast.Identifier)
+		if effect != "" || !ok || ident.Local {
+			return effect == ""
+		}
+		switch kind, fs := c.kindOf(ident.Value); kind {
+		case token.CONS:
+			effect = "cons " + ident.Value
+		case token.SUPP:
+			if inner := c.suppEffect(ident.Value, fs); inner != "" {
+				effect = fmt.Sprintf("supp %s, which calls %s", ident.Value, inner)
+			}
+		case "":
+			if builtin := c.builtin(ident.Value); builtin != nil && builtin.Effectful {
+				effect = "effectful builtin " + ident.Value
+			}
+		}
+		return true
+	})
+	return effect
+}
+
+// suppEffect returns the effect reached by the supp called name, caching the result.
+func (c *EffectChecker) suppEffect(name string, body ast.Expression) string {
+	if effect, ok := c.effects[name]; ok {
+		return effect
+	}
+	if c.visiting[name] {
+		return ""
+	}
+	c.visiting[name] = true
+	effect := c.effectOf(body)
+	delete(c.visiting, name)
+	c.effects[name] = effect
+	return effect
+}
+
+// kindOf returns the kind (PROC, CONS or SUPP) and body of the function a global
+// name refers to, or "" if it is not a user function.
+func (c *EffectChecker) kindOf(name string) (token.TokenType, ast.Expression) {
+	if fs, ok := c.functions[name]; ok {
+		return fs.Token.Type, fs.Body
+	}
+	if val, ok := c.globals.Get(name); ok {
+		if fn, ok := val.(*object.FunctionObject); ok {
+			return fn.Token.Type, fn.Body
+		}
+	}
+	return "", nil
+}
+
+// builtin returns the builtin a global name refers to: a host function set in
+// globals or a standard builtin. It returns nil for other names.
+func (c *EffectChecker) builtin(name string) *object.BuiltinObject {
+	if val, ok := c.globals.Get(name); ok {
+		builtin, _ := val.(*object.BuiltinObject)
+		return builtin
+	}
+	return builtins[name]
+}
+
+// consSource returns the name of the cons that produces the value of a pipeline
+// input, or "" if it is not produced by a cons.
+func (c *EffectChecker) consSource(input ast.Expression) string {
+	var producer ast.Expression
+	switch input := input.(type) {
+	case *ast.Identifier:
+		// A function on the left of `|>` is called and its result forwarded.
+		producer = input
+	case *ast.CallExpression:
+		producer = input.Function
+	case *ast.InfixExpression:
+		if !input.IsPipeline() {
+			return ""
+		}
+		producer = input.Right
+		if call, ok := input.Right.(*ast.CallExpression); ok {
+			producer = call.Function
+		}
+	}
+	if me, ok := producer.(*ast.MemberExpression); ok {
+		module := moduleNamed(me.Module.Value, c.modules, c.globals)
+		if module == nil {
+			return ""
+		}
+		if fs, msg := module.function(me.Module.Value, me.Member.Value); msg == "" && fs.Token.Type == token.CONS {
+			return me.String()
+		}
+		return ""
+	}
+	ident, ok := producer.(*ast.Identifier)
+	if !ok || ident.Local {
+		return ""
+	}
+	if kind, _ := c.kindOf(ident.Value); kind == token.CONS {
+		return ident.Value
+	}
+	return ""
+}
@@ -0,0 +1,1370 @@
+// Package engine은 Duet 프로그램을 검사하고 실행합니다.
+//
+// 호스트 프로그램은 CompileScript로 스크립트를 한 번 컴파일한 뒤, New로 만든 엔진의
+// Exec로 여러 번 실행하고, SetGlobal로 전역 값을 넣고, Call로 Duet 함수를 이름으로 호출합니다.
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"duet/ast"
+	"duet/object"
+	"duet/token"
+)
+
+// MAX_CALL_DEPTH는 EngineOptions.MaxDepth를 지정하지 않았을 때 사용하는 최대 호출 깊이입니다.
+const MAX_CALL_DEPTH = 10000
+
+// EngineOptions는 엔진의 실행 제한과 입출력을 설정합니다.
+// MaxDepth를 제외한 값이 0이면 해당 제한을 두지 않습니다.
+type EngineOptions struct {
+	// MaxDepth는 허용되는 최대 함수 호출 깊이입니다. 0이면 MAX_CALL_DEPTH를 사용합니다.
+	MaxDepth int
+	// MaxSteps는 Run 한 번에 평가할 수 있는 최대 노드 수입니다.
+	MaxSteps int64
+	// Timeout은 Run 한 번의 최대 실행 시간입니다.
+	Timeout time.Duration
+	// MaxAllocSize는 하나의 리스트 요소 수 또는 문자열 바이트 수의 최댓값입니다.
+	MaxAllocSize int
+	// UseVM이 참이면 트리 순회 대신 바이트코드 컴파일러와 스택 VM으로 실행합니다.
+	UseVM bool
+	// IO는 입출력 빌트인이 사용하는 표준 입출력과 파일 시스템입니다. nil이면 프로세스의 것을 사용합니다.
+	IO *object.IO
+}
+
+// ctxCheckInterval은 컨텍스트 취소 여부를 확인하는 평가 단계 간격입니다.
+const ctxCheckInterval = 1024
+
+// ExcutionEngine은 AST와 실행 환경(메모리)을 가집니다.
+type ExcutionEngine struct {
+	Program *ast.Program
+	Memory  *object.Memory
+	Options EngineOptions
+
+	callStack []string                       // 현재 실행 중인 사용자 함수 이름 (바깥쪽부터)
+	procs     []string                       // 실행 중인 proc 이름 (바깥쪽부터). 비어 있지 않으면 효과가 있는 함수를 호출할 수 없습니다.
+	yield     func(object.MemoryObject) bool // 실행 중인 제너레이터 supp에 값을 내보내는 함수
+	ctx       context.Context
+	steps     int64                           // 스케줄러에 아직 더하지 않은 평가 단계 수
+	sched     *scheduler                      // spawn과 채널이 쓰는 고루틴 스케줄러. 포크된 엔진과 공유됩니다.
+	modules   map[string]*object.ModuleObject // 이 엔진이 실행한 모듈 (경로별). 모듈은 엔진마다 한 번만 실행됩니다.
+}
+
+// NewExcutionEngine은 새로운 실행 엔진을 생성합니다.
+func NewExcutionEngine(program *ast.Program, memory *object.Memory) *ExcutionEngine {
+	if memory == nil {
+		memory = object.NewMemory()
+	}
+	return &ExcutionEngine{Program: program, Memory: memory}
+}
+
+// Run은 프로그램 실행의 진입점입니다.
+// ctx가 취소되거나 Options의 예산을 초과하면 Code가 BUDGET_EXCEEDED인 ErrorObject를 반환합니다.
+func (e *ExcutionEngine) Run(ctx context.Context) object.MemoryObject {
+	if err := check(e.Program, e.Memory); err != nil {
+		return err
+	}
+	return e.run(ctx, func() object.MemoryObject {
+		if e.Options.UseVM {
+			return e.runVM()
+		}
+		return e.Eval(e.Program, e.Memory)
+	})
+}
+
+// check는 실행 전에 식별자의 렉시컬 주소를 정하고, 정의되지 않은 이름과 효과 규칙 위반을 한꺼번에 보고합니다.
+func check(program *ast.Program, globals *object.Memory) *object.ErrorObject {
+	resolver := NewResolver(globals)
+	resolver.Resolve(program)
+	if errs := resolver.Errors(); len(errs) > 0 {
+		return newError("%s", strings.Join(errs, "\n"))
+	}
+	effects := NewEffectChecker(globals)
+	effects.Check(program)
+	if errs := effects.Errors(); len(errs) > 0 {
+		return newError("%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// run은 Options의 예산 안에서 body를 실행합니다.
+func (e *ExcutionEngine) run(ctx context.Context, body func() object.MemoryObject) object.MemoryObject {
+	if e.Options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.Options.Timeout)
+		defer cancel()
+	}
+	e.ctx = ctx
+	e.steps = 0
+	e.sched = newScheduler(ctx)
+	result := body()
+	// spawn으로 시작한 cons가 모두 끝날 때까지 기다리고, 그중 처음 발생한 에러를 보고합니다.
+	if failure := e.sched.wait(); failure != nil && !isError(result) {
+		return failure
+	}
+	return result
+}
+
+// Eval은 AST 노드를 받아 평가하고 MemoryObject를 반환하는 핵심 함수입니다.
+func (e *ExcutionEngine) Eval(node ast.Node, mem *object.Memory) object.MemoryObject {
+	if err := e.step(); err != nil {
+		return err
+	}
+
+	switch node := node.(type) {
+	// 문 (Statements)
+	case *ast.Program:
+		return e.evalProgram(node, mem)
+	case *ast.ExpressionStatement:
+		return e.Eval(node.Expression, mem)
+	case *ast.FunctionStatement:
+		mem.Set(string(node.Name.Value), newFunction(node, mem))
+		return nil // 함수 정의는 값을 반환하지 않습니다.
+	case *ast.ImportStatement:
+		return e.importModule(node, mem)
+
+	case *ast.FailExpression:
+		return &object.FailObject{Message: node.Message}
+
+	// 표현식 (Expressions)
+	case *ast.Identifier:
+		return e.evalIdentifier(node, mem)
+	case *ast.MemberExpression:
+		return e.evalMember(node, mem)
+	case *ast.IntegerLiteral:
+		return &object.IntegerObject{Value: node.Value}
+	case *ast.FloatLiteral:
+		return &object.FloatObject{Value: node.Value}
+	case *ast.StringLiteral:
+		return &object.StringObject{Value: node.Value}
+	case *ast.BooleanLiteral:
+		return nativeBoolToBooleanObject(node.Value)
+	case *ast.NilLiteral:
+		return object.Nil
+	case *ast.PrefixExpression:
+		right := e.Eval(node.Right, mem)
+		if isError(right) {
+			return right
+		}
+		return evalPrefixExpression(node.Operator, right)
+	case *ast.SpawnExpression:
+		function := e.Eval(node.Call.Function, mem)
+		if isError(function) {
+			return function
+		}
+		args := e.evalExpressions(node.Call.Arguments, mem)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
+		}
+		return e.spawn(function, args)
+	case *ast.SelectExpression:
+		return e.evalSelectExpression(node, mem)
+	case *ast.YieldExpression:
+		value := e.Eval(node.Value, mem)
+		if isError(value) {
+			return value
+		}
+		return e.yieldValue(value)
+	case *ast.InfixExpression:
+		if node.Operator == "|>" {
+			return e.evalPipeline(node, mem, false)
+		}
+		if node.Operator == "||>" {
+			return e.evalParallelPipeline(node, mem)
+		}
+		left := e.Eval(node.Left, mem)
+		if isError(left) {
+			return left
+		}
+		right := e.Eval(node.Right, mem)
+		if isError(right) {
+			return right
+		}
+		if err := e.checkRepeatSize(node.Operator, left, right); err != nil {
+			return err
+		}
+		return e.checkSize(evalInfixExpression(node.Operator, left, right))
+	case *ast.IfExpression:
+		return e.evalIfExpression(node, mem)
+	case *ast.ForExpression:
+		return e.evalForExpression(node, mem)
+	case *ast.CallExpression:
+		function := e.Eval(node.Function, mem)
+		if isError(function) {
+			return function
+		}
+		args := e.evalExpressions(node.Arguments, mem)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
+		}
+		return e.applyFunction(function, args, false)
+	case *ast.MatchExpression:
+		return e.evalMatchExpression(node, mem)
+	case *ast.ListLiteral:
+		elements := e.evalExpressions(node.Elements, mem)
+		if len(elements) == 1 && isError(elements[0]) {
+			return elements[0]
+		}
+		return e.checkSize(object.NewList(elements))
+	case *ast.MapLiteral:
+		return e.evalMapLiteral(node, mem)
+	case *ast.IndexExpression:
+		left := e.Eval(node.Left, mem)
+		if isError(left) {
+			return left
+		}
+		index := e.Eval(node.Index, mem)
+		if isError(index) {
+			return index
+		}
+		return evalIndexExpression(left, index)
+	}
+
+	return nil
+}
+
+func evalIndexExpression(left, index object.MemoryObject) object.MemoryObject {
+	switch {
+	case left.Type() == object.LIST_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalListIndexExpression(left, index)
+	case left.Type() == object.MAP_OBJ:
+		return evalMapIndexExpression(left, index)
+	default:
+		return newError("index operator not supported: %s", left.Type())
+	}
+}
+
+func evalListIndexExpression(list, index object.MemoryObject) object.MemoryObject {
+	listObject := list.(*object.ListObject)
+	idx := index.(*object.IntegerObject).Value
+	max := int64(listObject.Len() - 1)
+	if idx < 0 || idx > max {
+		return object.Nil
+	}
+	return listObject.At(int(idx))
+}
+
+// evalPipeline은 `|>` 파이프라인을 평가합니다.
+// tail이 참이면 마지막 단계의 사용자 함수 호출을 tailCallObject로 반환합니다.
+func (e *ExcutionEngine) evalPipeline(node *ast.InfixExpression, mem *object.Memory, tail bool) object.MemoryObject {
+	left := e.Eval(node.Left, mem)
+	if isError(left) {
+		return left
+	}
+
+	left = e.pipeSource(left)
+	if isError(left) {
+		return left
+	}
+
+	// Case 1: The right side is a call expression, e.g., `data |> process(1, 2)`
+	if call, ok := node.Right.(*ast.CallExpression); ok {
+		function := e.Eval(call.Function, mem)
+		if isError(function) {
+			return function
+		}
+
+		args := e.evalExpressions(call.Arguments, mem)
+		if len(args) > 0 && isError(args[0]) {
+			return args[0]
+		}
+
+		if stream, ok := left.(*object.StreamObject); ok {
+			if result, ok := pipeStream(e.callFunction, stream, function, args); ok {
+				return result
+			}
+		}
+
+		allArgs := append([]object.MemoryObject{left}, args...)
+		if tail {
+			return e.tailApply(function, allArgs, true)
+		}
+		return e.applyFunction(function, allArgs, true)
+	}
+
+	// Case 2: The right side is an identifier or other expression that yields a function, e.g., `data |> process`
+	right := e.Eval(node.Right, mem)
+	if isError(right) {
+		return right
+	}
+
+	if stream, ok := left.(*object.StreamObject); ok {
+		if result, ok := pipeStream(e.callFunction, stream, right, nil); ok {
+			return result
+		}
+	}
+
+	if tail {
+		return e.tailApply(right, []object.MemoryObject{left}, true)
+	}
+	return e.applyFunction(right, []object.MemoryObject{left}, true)
+}
+
+// pipeSource는 파이프라인 왼쪽의 값을 돌려줍니다.
+func (e *ExcutionEngine) pipeSource(left object.MemoryObject) object.MemoryObject {
+	// If the left side is a zero-argument function (a supplier), invoke it
+	// so the pipeline forwards the produced value instead of the function object.
+	switch lf := left.(type) {
+	case *object.FunctionObject:
+		if len(lf.Parameters) == 0 {
+			return e.applyFunction(lf, []object.MemoryObject{}, true)
+		}
+	case *object.BuiltinObject:
+		// If left is a builtin and takes no args, call it to get its value.
+		// Most builtins expect args, so this is a best-effort behavior.
+		return e.applyFunction(lf, []object.MemoryObject{}, true)
+	}
+	return left
+}
+
+func (e *ExcutionEngine) evalProgram(program *ast.Program, mem *object.Memory) object.MemoryObject {
+	var result object.MemoryObject
+	for _, statement := range program.Statements {
+		result = e.Eval(statement, mem)
+
+		switch result := result.(type) {
+		case *object.ReturnValueObject:
+			return result.Value
+		case *object.ErrorObject:
+			return result
+		}
+	}
+	return result
+}
+
+func nativeBoolToBooleanObject(input bool) *object.BooleanObject {
+	if input {
+		return object.True
+	}
+	return object.False
+}
+
+func evalPrefixExpression(operator string, right object.MemoryObject) object.MemoryObject {
+	switch operator {
+	case "!":
+		return evalBangOperatorExpression(right)
+	case "-":
+		return evalMinusPrefixOperatorExpression(right)
+	default:
+		return newError("unknown operator: %s%s", operator, right.Type())
+	}
+}
+
+func evalInfixExpression(operator string, left, right object.MemoryObject) object.MemoryObject {
+	switch {
+	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
+		return evalIntegerInfixExpression(operator, left, right)
+	case left.Type() == object.FLOAT_OBJ && right.Type() == object.FLOAT_OBJ:
+		return evalFloatInfixExpression(operator, left, right)
+	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
+		return evalStringInfixExpression(operator, left, right)
+	case left.Type() == object.STRING_OBJ && right.Type() == object.INTEGER_OBJ:
+		return evalStringInfixExpression(operator, left, right)
+	case operator == "==":
+		if left.Type() == object.BOOLEAN_OBJ && right.Type() == object.BOOLEAN_OBJ {
+			return nativeBoolToBooleanObject(left.(*object.BooleanObject).Value == right.(*object.BooleanObject).Value)
+		}
+		return nativeBoolToBooleanObject(left == right)
+	case operator == "!=":
+		if left.Type() == object.BOOLEAN_OBJ && right.Type() == object.BOOLEAN_OBJ {
+			return nativeBoolToBooleanObject(left.(*object.BooleanObject).Value != right.(*object.BooleanObject).Value)
+		}
+		return nativeBoolToBooleanObject(left != right)
+	case left.Type() != right.Type():
+		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func evalBangOperatorExpression(right object.MemoryObject) object.MemoryObject {
+	switch right {
+	case object.True:
+		return object.False
+	case object.False:
+		return object.True
+	case object.Nil:
+		return object.True
+	default:
+		return object.False
+	}
+}
+
+func evalMinusPrefixOperatorExpression(right object.MemoryObject) object.MemoryObject {
+	if right.Type() == object.INTEGER_OBJ {
+		value := right.(*object.IntegerObject).Value
+		return &object.IntegerObject{Value: -value}
+	}
+	if right.Type() == object.FLOAT_OBJ {
+		value := right.(*object.FloatObject).Value
+		return &object.FloatObject{Value: -value}
+	}
+	return newError("unknown operator: -%s", right.Type())
+}
+
+func evalIntegerInfixExpression(operator string, left, right object.MemoryObject) object.MemoryObject {
+	leftVal := left.(*object.IntegerObject).Value
+	rightVal := right.(*object.IntegerObject).Value
+	switch operator {
+	case "+":
+		return &object.IntegerObject{Value: leftVal + rightVal}
+	case "-":
+		return &object.IntegerObject{Value: leftVal - rightVal}
+	case "*":
+		return &object.IntegerObject{Value: leftVal * rightVal}
+	case "/":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
+		return &object.IntegerObject{Value: leftVal / rightVal}
+	case "%":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
+		return &object.IntegerObject{Value: leftVal % rightVal}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func evalFloatInfixExpression(operator string, left, right object.MemoryObject) object.MemoryObject {
+	leftVal := left.(*object.FloatObject).Value
+	rightVal := right.(*object.FloatObject).Value
+	switch operator {
+	case "+":
+		return &object.FloatObject{Value: leftVal + rightVal}
+	case "-":
+		return &object.FloatObject{Value: leftVal - rightVal}
+	case "*":
+		return &object.FloatObject{Value: leftVal * rightVal}
+	case "/":
+		if rightVal == 0.0 {
+			return newError("division by zero")
+		}
+		return &object.FloatObject{Value: leftVal / rightVal}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// evalStringInfixExpression는 STRING과 STRING, 또는 STRING과 INTEGER(반복) 연산을 평가합니다.
+// 최적화기가 실행되지 않는 코드도 접어 보므로, 맞지 않는 타입에는 패닉 대신 에러를 반환합니다.
+func evalStringInfixExpression(operator string, left, right object.MemoryObject) object.MemoryObject {
+	leftVal := left.(*object.StringObject).Value
+	switch right := right.(type) {
+	case *object.StringObject:
+		switch operator {
+		case "+":
+			return &object.StringObject{Value: leftVal + right.Value}
+		case "==":
+			return nativeBoolToBooleanObject(leftVal == right.Value)
+		}
+	case *object.IntegerObject:
+		switch operator {
+		case "*":
+			if right.Value <= 0 {
+				return &object.StringObject{Value: ""}
+			}
+			if len(leftVal) > 0 && right.Value > int64(math.MaxInt/len(leftVal)) {
+				return newError("string repetition too large: %d * %d bytes", right.Value, len(leftVal))
+			}
+			return &object.StringObject{Value: strings.Repeat(leftVal, int(right.Value))}
+		default:
+			return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+		}
+	}
+	return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+}
+
+func (e *ExcutionEngine) evalIfExpression(ie *ast.IfExpression, mem *object.Memory) object.MemoryObject {
+	condition := e.Eval(ie.Condition, mem)
+	if isError(condition) {
+		return condition
+	}
+	if isTruthy(condition) {
+		return e.Eval(ie.Consequence, mem)
+	} else if ie.Alternative != nil {
+		return e.Eval(ie.Alternative, mem)
+	} else {
+		return object.Nil
+	}
+}
+
+// evalSelectExpression은 모든 경우의 채널과 보낼 값을 평가한 뒤, 진행할 수 있는 첫 경우를 실행합니다.
+// 받은 값은 그 경우의 본문에서 as 뒤의 이름으로 쓸 수 있습니다.
+func (e *ExcutionEngine) evalSelectExpression(se *ast.SelectExpression, mem *object.Memory) object.MemoryObject {
+	operands := make([][]object.MemoryObject, len(se.Cases))
+	for i, c := range se.Cases {
+		operands[i] = e.evalExpressions(c.Call.Arguments, mem)
+		if len(operands[i]) == 1 && isError(operands[i][0]) {
+			return operands[i][0]
+		}
+	}
+	cases, err := selectCases(se.Cases, operands)
+	if err != nil {
+		return err
+	}
+
+	index, value := e.scheduler().choose(cases, se.Default == nil)
+	if index < 0 {
+		return e.Eval(se.Default, mem)
+	}
+	if isError(value) {
+		return value
+	}
+	c := se.Cases[index]
+	if c.Variable != nil {
+		mem = object.NewLocalMemory(mem, []object.MemoryObject{value})
+	}
+	return e.Eval(c.Body, mem)
+}
+
+func (e *ExcutionEngine) evalMatchExpression(me *ast.MatchExpression, mem *object.Memory) object.MemoryObject {
+	subject := e.Eval(me.Subject, mem)
+	if isError(subject) {
+		return subject
+	}
+
+	for _, c := range me.Cases {
+		condition := e.Eval(c.Condition, mem)
+		if isError(condition) {
+			return condition
+		}
+
+		if isTruthy(condition) {
+			return e.Eval(c.Consequence, mem)
+		}
+	}
+
+	if me.Default != nil {
+		return e.Eval(me.Default, mem)
+	}
+
+	return object.Nil // 일치하는 케이스가 없고 기본값도 없는 경우
+}
+
+func (e *ExcutionEngine) evalForExpression(fe *ast.ForExpression, mem *object.Memory) object.MemoryObject {
+	results := []object.MemoryObject{}
+	pairs := object.NewMap()
+
+	err := e.evalForGenerators(fe.Generators, mem, func(loopMem *object.Memory) object.MemoryObject {
+		if fe.MapKey == nil {
+			result := e.Eval(fe.Body, loopMem)
+			if isError(result) {
+				return result
+			}
+			results = append(results, result)
+			if max := e.Options.MaxAllocSize; max > 0 && len(results) > max {
+				return e.allocError(len(results))
+			}
+			return nil
+		}
+
+		key := e.Eval(fe.MapKey, loopMem)
+		if isError(key) {
+			return key
+		}
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", key.Type())
+		}
+		value := e.Eval(fe.Body, loopMem)
+		if isError(value) {
+			return value
+		}
+		pairs = pairs.Set(hashKey.HashKey(), object.MapPair{Key: key, Value: value})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if fe.MapKey != nil {
+		return pairs
+	}
+	return object.NewList(results)
+}
+
+// evalForGenerators는 생성자(generator)들을 차례로 중첩 순회하며,
+// 모든 변수가 바인딩되고 조건을 통과한 스코프마다 emit을 호출합니다.
+// emit이나 순회 중 에러가 발생하면 그 에러를 반환합니다.
+func (e *ExcutionEngine) evalForGenerators(generators []*ast.ForGenerator, mem *object.Memory, emit func(*object.Memory) object.MemoryObject) object.MemoryObject {
+	if len(generators) == 0 {
+		return emit(mem)
+	}
+	gen := generators[0]
+
+	collection := e.Eval(gen.Collection, mem)
+	if isError(collection) {
+		return collection
+	}
+
+	iterate := func(key, value object.MemoryObject) object.MemoryObject {
+		// 슬롯 순서는 Resolver.resolveFor와 같습니다: 키 변수, 값 변수.
+		slots := []object.MemoryObject{value}
+		if gen.Key != nil {
+			slots = []object.MemoryObject{key, value}
+		}
+		loopMem := object.NewLocalMemory(mem, slots)
+
+		if gen.Condition != nil {
+			condition := e.Eval(gen.Condition, loopMem)
+			if isError(condition) {
+				return condition
+			}
+			if !isTruthy(condition) {
+				return nil
+			}
+		}
+		return e.evalForGenerators(generators[1:], loopMem, emit)
+	}
+
+	switch coll := collection.(type) {
+	case *object.ListObject:
+		for i, el := range coll.All() {
+			if err := iterate(&object.IntegerObject{Value: int64(i)}, el); err != nil {
+				return err
+			}
+		}
+	case *object.StreamObject:
+		// 스트림은 요소를 하나씩 당겨 오며 순회합니다. 키 변수에는 순번이 바인딩됩니다.
+		i := 0
+		for el := range coll.All() {
+			if isError(el) {
+				return el
+			}
+			if err := iterate(&object.IntegerObject{Value: int64(i)}, el); err != nil {
+				coll.Close()
+				return err
+			}
+			i++
+		}
+	case *object.MapObject:
+		// 변수가 하나이면 키를, 둘이면 키와 값을 바인딩합니다.
+		for _, pair := range coll.SortedPairs() {
+			value := pair.Key
+			if gen.Key != nil {
+				value = pair.Value
+			}
+			if err := iterate(pair.Key, value); err != nil {
+				return err
+			}
+		}
+	default:
+		return newError("for loop must iterate over a list, map or stream, got %s", collection.Type())
+	}
+
+	return nil
+}
+
+func (e *ExcutionEngine) evalMapLiteral(node *ast.MapLiteral, mem *object.Memory) object.MemoryObject {
+	pairs := object.NewMap()
+
+	for keyNode, valueNode := range node.Pairs {
+		key := e.Eval(keyNode, mem)
+		if isError(key) {
+			return key
+		}
+
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", key.Type())
+		}
+
+		value := e.Eval(valueNode, mem)
+		if isError(value) {
+			return value
+		}
+
+		hashed := hashKey.HashKey()
+		pairs = pairs.Set(hashed, object.MapPair{Key: key, Value: value})
+	}
+
+	return pairs
+}
+
+func evalMapIndexExpression(m, index object.MemoryObject) object.MemoryObject {
+	mapObject := m.(*object.MapObject)
+	key, ok := index.(object.Hashable)
+	if !ok {
+		return newError("unusable as hash key: %s", index.Type())
+	}
+	pair, ok := mapObject.Get(key.HashKey())
+	if !ok {
+		return object.Nil
+	}
+	return pair.Value
+}
+
+func (e *ExcutionEngine) evalIdentifier(node *ast.Identifier, mem *object.Memory) object.MemoryObject {
+	var val object.MemoryObject
+	var ok bool
+	if node.Local {
+		val, ok = mem.Lookup(node.Depth, node.Slot), true
+	} else {
+		val, ok = mem.Get(node.Value)
+	}
+	if ok {
+		// If the identifier refers to a zero-argument supplier (supp/esupp),
+		// invoke it and return the produced value instead of the function object.
+		if fn, ok := val.(*object.FunctionObject); ok {
+			if fn.Token.Type == token.SUPP && len(fn.Parameters) == 0 {
+				produced := e.applyFunction(fn, []object.MemoryObject{}, false)
+				return produced
+			}
+		}
+		return val
+	}
+	if builtin, ok := builtins[node.Value]; ok {
+		return builtin
+	}
+	return newError("identifier not found: %s", node.Value)
+}
+
+// evalMember는 모듈이 내보낸 함수를 찾습니다. 식별자처럼 매개변수가 없는 supp은 호출한 결과를 반환합니다.
+func (e *ExcutionEngine) evalMember(node *ast.MemberExpression, mem *object.Memory) object.MemoryObject {
+	module := e.evalIdentifier(node.Module, mem)
+	if isError(module) {
+		return module
+	}
+	val := member(module, node.Module.Value, node.Member.Value)
+	if fn, ok := val.(*object.FunctionObject); ok && fn.Token.Type == token.SUPP && len(fn.Parameters) == 0 {
+		return e.applyFunction(fn, []object.MemoryObject{}, false)
+	}
+	return val
+}
+
+func (e *ExcutionEngine) evalExpressions(exps []ast.Expression, mem *object.Memory) []object.MemoryObject {
+	var result []object.MemoryObject
+	for _, exp := range exps {
+		evaluated := e.Eval(exp, mem)
+		if _, ok := exp.(*ast.FailExpression); ok {
+			return []object.MemoryObject{evaluated}
+		}
+
+		if isError(evaluated) {
+			return []object.MemoryObject{evaluated}
+		}
+		result = append(result, evaluated)
+	}
+	return result
+}
+
+// tailCallObject는 꼬리 위치(tail position)의 함수 호출을 나타냅니다.
+// evalTail이 호출을 바로 실행하는 대신 이 객체를 반환하면, applyFunction이 루프에서
+// 이어서 실행하므로 재귀 깊이와 관계없이 Go 스택이 늘어나지 않습니다.
+// applyFunction 바깥으로는 절대 노출되지 않습니다.
+type tailCallObject struct {
+	fn   *object.FunctionObject
+	args []object.MemoryObject
+}
+
+func (tc *tailCallObject) Type() object.MemoryObjectType { return object.TAIL_CALL_OBJ }
+func (tc *tailCallObject) Inspect() string               { return "tail call " + tc.fn.Name.Value }
+
+// evalTail은 함수 본문처럼 꼬리 위치에 있는 노드를 평가합니다.
+// if, match의 분기와 파이프라인의 마지막 단계가 꼬리 위치로 이어지며,
+// 그곳의 사용자 함수 호출은 tailCallObject로 반환됩니다.
+func (e *ExcutionEngine) evalTail(node ast.Expression, mem *object.Memory) object.MemoryObject {
+	switch node := node.(type) {
+	case *ast.IfExpression:
+		condition := e.Eval(node.Condition, mem)
+		if isError(condition) {
+			return condition
+		}
+		if isTruthy(condition) {
+			return e.evalTail(node.Consequence, mem)
+		} else if node.Alternative != nil {
+			return e.evalTail(node.Alternative, mem)
+		}
+		return object.Nil
+	case *ast.MatchExpression:
+		subject := e.Eval(node.Subject, mem)
+		if isError(subject) {
+			return subject
+		}
+		for _, c := range node.Cases {
+			condition := e.Eval(c.Condition, mem)
+			if isError(condition) {
+				return condition
+			}
+			if isTruthy(condition) {
+				return e.evalTail(c.Consequence, mem)
+			}
+		}
+		if node.Default != nil {
+			return e.evalTail(node.Default, mem)
+		}
+		return object.Nil
+	case *ast.InfixExpression:
+		if node.Operator == "|>" {
+			return e.evalPipeline(node, mem, true)
+		}
+	case *ast.CallExpression:
+		function := e.Eval(node.Function, mem)
+		if isError(function) {
+			return function
+		}
+		args := e.evalExpressions(node.Arguments, mem)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
+		}
+		return e.tailApply(function, args, false)
+	}
+	return e.Eval(node, mem)
+}
+
+// tailApply는 사용자 함수 호출을 지연시키고, 그 밖의 호출은 바로 실행합니다.
+func (e *ExcutionEngine) tailApply(fn object.MemoryObject, args []object.MemoryObject, isPipeline bool) object.MemoryObject {
+	if function, ok := fn.(*object.FunctionObject); ok {
+		return &tailCallObject{fn: function, args: args}
+	}
+	return e.applyFunction(fn, args, isPipeline)
+}
+
+func (e *ExcutionEngine) applyFunction(fn object.MemoryObject, args []object.MemoryObject, isPipeline bool) object.MemoryObject {
+	switch fn := fn.(type) {
+	case *object.FunctionObject:
+		// 호출 깊이를 제한하여 Go 스택 오버플로로 프로세스가 종료되는 것을 막습니다.
+		if len(e.callStack) >= e.maxDepth() {
+			return e.stackOverflowError(fn)
+		}
+		e.callStack = append(e.callStack, fn.Name.Value)
+		defer func() { e.callStack = e.callStack[:len(e.callStack)-1] }()
+
+		// 꼬리 호출로 이어진 함수들은 모두 같은 최종 값을 반환하므로,
+		// 반환 타입 검사와 메모이제이션 캐시 저장은 루프가 끝난 뒤 한 번씩만 수행합니다.
+		var pending []*object.FunctionObject
+		var memos []memoCall
+		var evaluated object.MemoryObject
+		inProc := false
+		for {
+			if err := checkArguments(fn, args); err != nil {
+				return err
+			}
+			if err := e.checkEffect(fn); err != nil {
+				return err
+			}
+			if fn.Token.Type == token.PROC && !inProc {
+				inProc = true
+				e.procs = append(e.procs, fn.Name.Value)
+				defer func() { e.procs = e.procs[:len(e.procs)-1] }()
+			}
+
+			if fn.Generator {
+				// 제너레이터 supp은 본문을 바로 실행하지 않고, 요청받을 때마다 yield까지 실행하는 스트림을 반환합니다.
+				body, mem := fn.Body, extendFunctionMem(fn, args)
+				evaluated = e.newGenerator(func() object.MemoryObject { return e.evalDiscard(body, mem) })
+				break
+			}
+
+			if cache := memoOf(fn); cache != nil {
+				if err := e.checkMemo(fn, cache); err != nil {
+					return err
+				}
+				if key, ok := memoKey(args); ok {
+					if cached, ok := cache.get(key); ok {
+						evaluated = cached
+						break
+					}
+					memos = append(memos, memoCall{cache: cache, key: key})
+				}
+			}
+
+			extendedMem := extendFunctionMem(fn, args)
+			evaluated = e.evalTail(fn.Body, extendedMem)
+
+			if tc, ok := evaluated.(*tailCallObject); ok {
+				if !slices.Contains(pending, fn) {
+					pending = append(pending, fn)
+				}
+				fn, args = tc.fn, tc.args
+				e.callStack[len(e.callStack)-1] = fn.Name.Value
+				continue
+			}
+			break
+		}
+
+		// Unwrap return value if it's wrapped in a ReturnValueObject
+		if returnValue, ok := evaluated.(*object.ReturnValueObject); ok {
+			evaluated = returnValue.Value
+		}
+
+		evaluated = checkReturnValue(fn, evaluated)
+		for i := len(pending) - 1; i >= 0 && !isError(evaluated); i-- {
+			if pending[i] != fn {
+				evaluated = checkReturnValue(pending[i], evaluated)
+			}
+		}
+		if !isError(evaluated) {
+			for _, m := range memos {
+				m.cache.put(m.key, evaluated)
+			}
+		}
+		return evaluated
+
+	case *object.BuiltinObject:
+		return e.applyBuiltin(fn, args, e.callFunction)
+	default:
+		return newError("not a function: %s", fn.Type())
+	}
+}
+
+// applyBuiltin은 빌트인 함수를 호출합니다. call은 고차 빌트인이 인자로 받은 함수를 호출할 때 사용됩니다.
+func (e *ExcutionEngine) applyBuiltin(fn *object.BuiltinObject, args []object.MemoryObject, call object.Caller) object.MemoryObject {
+	if err := e.checkEffect(fn); err != nil {
+		return err
+	}
+	// If any argument is a FAIL object, just return it immediately.
+	// This allows built-ins to participate in error-handling pipelines.
+	for i, arg := range args {
+		if arg.Type() == object.FAIL_OBJ && !fn.AcceptsFail {
+			return arg
+		}
+		if stream, ok := arg.(*object.StreamObject); ok && !fn.Streams {
+			collected, ok := stream.CollectLimit(e.Options.MaxAllocSize)
+			if !ok {
+				return budgetError("stream has more than %d elements", e.Options.MaxAllocSize)
+			}
+			if isError(collected) {
+				return collected
+			}
+			args[i] = collected
+		}
+	}
+	// 결과가 인자보다 훨씬 클 수 있는 빌트인은 할당하기 전에 크기를 확인합니다.
+	if max := e.Options.MaxAllocSize; max > 0 && fn.Size != nil {
+		if size := fn.Size(args...); size > max {
+			return e.allocError(size)
+		}
+	}
+	if fn.HigherOrder != nil {
+		return e.checkSize(fn.HigherOrder(call, args...))
+	}
+	if fn.Blocking != nil {
+		return e.checkSize(fn.Blocking(e.scheduler(), args...))
+	}
+	if fn.IO != nil {
+		return e.checkSize(fn.IO(e.io(), args...))
+	}
+	if fn.Parallel != nil {
+		var forks []*ExcutionEngine
+		result := fn.Parallel(e.spawner(&forks), args...)
+		e.join(forks)
+		return e.checkSize(result)
+	}
+	return e.checkSize(fn.Fn(args...))
+}
+
+// yieldValue는 value를 실행 중인 제너레이터의 스트림으로 내보냅니다.
+// 소비자가 스트림을 닫았으면 streamClosed를 반환하여 제너레이터 본문을 중단시킵니다.
+func (e *ExcutionEngine) yieldValue(value object.MemoryObject) object.MemoryObject {
+	if e.yield == nil {
+		return newError("yield outside of a generator")
+	}
+	if !e.yield(value) {
+		return streamClosed
+	}
+	return object.Nil
+}
+
+// evalDiscard는 값을 쓰지 않을 본문(제너레이터 supp의 본문)을 평가합니다.
+// 꼬리 위치의 for는 결과 리스트를 만들지 않으므로, 긴 스트림을 yield해도 메모리가 늘지 않습니다.
+func (e *ExcutionEngine) evalDiscard(node ast.Expression, mem *object.Memory) object.MemoryObject {
+	switch node := node.(type) {
+	case *ast.ForExpression:
+		return e.evalForGenerators(node.Generators, mem, func(loopMem *object.Memory) object.MemoryObject {
+			if node.MapKey != nil {
+				if key := e.Eval(node.MapKey, loopMem); isError(key) {
+					return key
+				}
+			}
+			if result := e.evalDiscard(node.Body, loopMem); isError(result) {
+				return result
+			}
+			return nil
+		})
+	case *ast.IfExpression:
+		condition := e.Eval(node.Condition, mem)
+		if isError(condition) {
+			return condition
+		}
+		if isTruthy(condition) {
+			return e.evalDiscard(node.Consequence, mem)
+		} else if node.Alternative != nil {
+			return e.evalDiscard(node.Alternative, mem)
+		}
+		return object.Nil
+	case *ast.MatchExpression:
+		subject := e.Eval(node.Subject, mem)
+		if isError(subject) {
+			return subject
+		}
+		for _, c := range node.Cases {
+			condition := e.Eval(c.Condition, mem)
+			if isError(condition) {
+				return condition
+			}
+			if isTruthy(condition) {
+				return e.evalDiscard(c.Consequence, mem)
+			}
+		}
+		if node.Default != nil {
+			return e.evalDiscard(node.Default, mem)
+		}
+		return object.Nil
+	}
+	if result := e.Eval(node, mem); isError(result) {
+		return result
+	}
+	return object.Nil
+}
+
+// newFunction은 함수 정의문으로 mem에 바인딩될 함수 객체를 만듭니다.
+func newFunction(node *ast.FunctionStatement, mem *object.Memory) *object.FunctionObject {
+	fn := &object.FunctionObject{
+		Name:       node.Name,
+		Token:      node.Token,
+		Parameters: node.Parameters,
+		ReturnType: node.ReturnType,
+		Body:       node.Body,
+		Mem:        mem,
+		Generator:  node.Token.Type == token.SUPP && isGenerator(node.Body),
+	}
+	if a := node.Annotation("memo"); a != nil {
+		size, _ := ast.MemoSize(a)
+		fn.Memo = newMemoCache(size)
+	}
+	return fn
+}
+
+// memoCall은 결과가 정해지면 캐시에 저장할 메모이제이션 호출입니다.
+type memoCall struct {
+	cache *memoCache
+	key   string
+}
+
+// checkMemo는 @memo proc가 처음 호출될 때, 본문에서 닿을 수 있는 함수 중에
+// cons나 입출력 빌트인이 없는지 확인합니다. 부수 효과가 있으면 캐시가 결과를 바꾸기 때문입니다.
+func (e *ExcutionEngine) checkMemo(fn *object.FunctionObject, cache *memoCache) *object.ErrorObject {
+	if cache.checked.Load() {
+		return nil
+	}
+	if effect := findEffect(fn, map[*object.FunctionObject]bool{}); effect != "" {
+		return newError("@memo proc %s cannot be cached: it calls %s", fn.Name.Value, effect)
+	}
+	cache.checked.Store(true)
+	return nil
+}
+
+// findEffect는 fn 본문에서 (다른 함수를 거쳐서라도) 호출될 수 있는 cons나 입출력 빌트인의
+// 설명을 반환합니다. 없으면 빈 문자열을 반환합니다.
+func findEffect(fn *object.FunctionObject, visited map[*object.FunctionObject]bool) string {
+	if visited[fn] {
+		return ""
+	}
+	visited[fn] = true
+
+	params := map[string]bool{}
+	for _, param := range fn.Parameters {
+		params[param.Name.Value] = true
+	}
+	effect := ""
+	walkExpression(fn.Body, func(ident *ast.Identifier, bound, _ bool) {
+		if effect != "" || bound || params[ident.Value] {
+			return
+		}
+		if val, ok := fn.Mem.Get(ident.Value); ok {
+			if callee, ok := val.(*object.FunctionObject); ok {
+				if callee.Token.Type == token.CONS {
+					effect = "cons " + callee.Name.Value
+				} else if inner := findEffect(callee, visited); inner != "" {
+					effect = inner + " (via " + callee.Name.Value + ")"
+				}
+			}
+			if builtin, ok := val.(*object.BuiltinObject); ok && builtin.Effectful {
+				effect = "IO builtin " + ident.Value
+			}
+			return
+		}
+		if builtin, ok := builtins[ident.Value]; ok && builtin.Effectful {
+			effect = "IO builtin " + ident.Value
+		}
+	})
+	return effect
+}
+
+// checkEffect는 proc이 실행 중일 때 cons나 효과가 있는 빌트인을 호출하지 못하게 합니다.
+// 컴파일 시점의 검사는 이름만 보므로, fn이나 any 매개변수로 전달된 함수는 여기서 막습니다.
+func (e *ExcutionEngine) checkEffect(fn object.MemoryObject) *object.ErrorObject {
+	if len(e.procs) == 0 {
+		return nil
+	}
+	proc := e.procs[len(e.procs)-1]
+	switch fn := fn.(type) {
+	case *object.FunctionObject:
+		if fn.Token.Type == token.CONS {
+			return newError("effect error: proc %s calls cons %s", proc, fn.Name.Value)
+		}
+	case *object.BuiltinObject:
+		if fn.Effectful {
+			return newError("effect error: proc %s calls an effectful builtin", proc)
+		}
+	}
+	return nil
+}
+
+// checkArguments는 인자의 개수와 타입이 함수 시그니처와 맞는지 확인합니다.
+func checkArguments(fn *object.FunctionObject, args []object.MemoryObject) *object.ErrorObject {
+	// Check if the number of arguments matches the function's signature
+	if len(args) != len(fn.Parameters) {
+		return newError("wrong number of arguments: got=%d, want=%d", len(args), len(fn.Parameters))
+	}
+
+	// Check if the argument types match the function's signature
+	for i, param := range fn.Parameters {
+		expectedType := param.Type.Value
+		actualType := args[i].Type()
+		isFallibleParam := strings.HasSuffix(expectedType, "?")
+		cleanExpectedType := strings.TrimSuffix(expectedType, "?")
+
+		if isFallibleParam && actualType == object.FAIL_OBJ {
+			continue // A fallible parameter accepts a FAIL object.
+		}
+
+		// This is a simplified type check. A more robust implementation
+		// would use a map or a more flexible system.
+		if !isTypeMatch(actualType, cleanExpectedType) {
+			return newError("type error: wrong type for argument %s. got=%s, want=%s", param.Name.Value, actualType, cleanExpectedType)
+		}
+	}
+	return nil
+}
+
+// checkReturnValue는 반환 값이 함수의 반환 타입과 맞는지 확인하고, 맞으면 값을 그대로 반환합니다.
+func checkReturnValue(fn *object.FunctionObject, evaluated object.MemoryObject) object.MemoryObject {
+	if fn.ReturnType == nil || isError(evaluated) {
+		return evaluated
+	}
+	expectedType := fn.ReturnType.Value
+	actualType := evaluated.Type()
+
+	// For errorable functions, allow returning FAIL if the return type is marked as fallible (e.g., "str?").
+	isFallibleDecl := strings.HasSuffix(expectedType, "?")
+	if actualType == object.FAIL_OBJ {
+		if isFallibleDecl {
+			return evaluated // It's a FAIL object and the return type is fallible, so pass it through.
+		}
+		return newError("type error: function %s returned FAIL, but return type '%s' is not marked as fallible (use '%s?')", fn.Name.Value, expectedType, expectedType)
+	}
+
+	// Strip '?' for normal type matching.
+	cleanExpectedType := strings.TrimSuffix(expectedType, "?")
+	if !isTypeMatch(actualType, cleanExpectedType) {
+		return newError("type error: function %s returned %s, but expected %s", fn.Name.Value, actualType, expectedType)
+	}
+	return evaluated
+}
+
+func (e *ExcutionEngine) maxDepth() int {
+	if e.Options.MaxDepth > 0 {
+		return e.Options.MaxDepth
+	}
+	return MAX_CALL_DEPTH
+}
+
+// stackOverflowError는 호출 깊이 초과 에러를 최근 호출 경로와 함께 만듭니다.
+// 같은 함수가 연속으로 호출된 구간은 한 줄로 묶습니다.
+func (e *ExcutionEngine) stackOverflowError(fn *object.FunctionObject) *object.ErrorObject {
+	const maxFrames = 10
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "stack overflow: maximum call depth %d exceeded while calling %s", e.maxDepth(), fn.Name.Value)
+	out.WriteString("\nrecent calls (innermost first):")
+
+	frames := 0
+	for i := len(e.callStack) - 1; i >= 0 && frames < maxFrames; frames++ {
+		name := e.callStack[i]
+		count := 0
+		for i >= 0 && e.callStack[i] == name {
+			count++
+			i--
+		}
+		if count > 1 {
+			fmt.Fprintf(&out, "\n\tin %s (repeated %d times)", name, count)
+		} else {
+			fmt.Fprintf(&out, "\n\tin %s", name)
+		}
+	}
+	return &object.ErrorObject{Message: out.String()}
+}
+
+// step은 평가 단계 하나를 소비하고, 예산을 초과했거나 컨텍스트가 끝났으면 에러를 반환합니다.
+// 단계 예산은 포크된 엔진과 spawn된 cons를 포함한 Run 전체가 나눠 씁니다.
+func (e *ExcutionEngine) step() *object.ErrorObject {
+	e.steps++
+	if e.Options.MaxSteps > 0 && e.scheduler().steps.Load()+e.steps > e.Options.MaxSteps {
+		return budgetError("step limit of %d exceeded", e.Options.MaxSteps)
+	}
+	if e.steps == ctxCheckInterval {
+		e.flushSteps()
+		if e.ctx != nil {
+			return contextError(e.ctx)
+		}
+	}
+	return nil
+}
+
+// flushSteps는 이 엔진이 센 단계를 스케줄러의 공유 카운터에 더합니다.
+func (e *ExcutionEngine) flushSteps() {
+	e.scheduler().steps.Add(e.steps)
+	e.steps = 0
+}
+
+// contextError는 ctx가 끝났으면 그 이유에 맞는 예산 초과 에러를, 아니면 nil을 반환합니다.
+func contextError(ctx context.Context) *object.ErrorObject {
+	switch ctx.Err() {
+	case nil:
+		return nil
+	case context.DeadlineExceeded:
+		return budgetError("time limit exceeded")
+	default:
+		return budgetError("execution cancelled")
+	}
+}
+
+// checkSize는 새로 만들어진 값이 MaxAllocSize를 넘는지 확인합니다.
+func (e *ExcutionEngine) checkSize(obj object.MemoryObject) object.MemoryObject {
+	if e.Options.MaxAllocSize <= 0 {
+		return obj
+	}
+	switch obj := obj.(type) {
+	case *object.ListObject:
+		if obj.Len() > e.Options.MaxAllocSize {
+			return e.allocError(obj.Len())
+		}
+	case *object.StringObject:
+		if len(obj.Value) > e.Options.MaxAllocSize {
+			return e.allocError(len(obj.Value))
+		}
+	}
+	return obj
+}
+
+// checkRepeatSize는 문자열 반복(`str * int`)의 결과 크기를 할당하기 전에 확인합니다.
+func (e *ExcutionEngine) checkRepeatSize(operator string, left, right object.MemoryObject) *object.ErrorObject {
+	if e.Options.MaxAllocSize <= 0 || operator != "*" {
+		return nil
+	}
+	s, ok := left.(*object.StringObject)
+	if !ok {
+		return nil
+	}
+	n, ok := right.(*object.IntegerObject)
+	if !ok || n.Value <= 0 || len(s.Value) == 0 {
+		return nil
+	}
+	if n.Value > int64(e.Options.MaxAllocSize/len(s.Value)) {
+		return e.allocError(int(min(n.Value*int64(len(s.Value)), int64(^uint(0)>>1))))
+	}
+	return nil
+}
+
+func (e *ExcutionEngine) allocError(size int) *object.ErrorObject {
+	return budgetError("allocation of size %d exceeds limit of %d", size, e.Options.MaxAllocSize)
+}
+
+func budgetError(format string, a ...interface{}) *object.ErrorObject {
+	return &object.ErrorObject{Message: "budget exceeded: " + fmt.Sprintf(format, a...), Code: object.BUDGET_EXCEEDED}
+}
+
+// callFunction은 빌트인 함수에 전달되는 Caller 구현입니다.
+func (e *ExcutionEngine) callFunction(fn object.MemoryObject, args ...object.MemoryObject) object.MemoryObject {
+	return e.applyFunction(fn, args, false)
+}
+
+func isTypeMatch(actual object.MemoryObjectType, expected string) bool {
+	switch expected {
+	case "int":
+		return actual == object.INTEGER_OBJ
+	case "float":
+		return actual == object.FLOAT_OBJ
+	case "str":
+		return actual == object.STRING_OBJ
+	case "bool":
+		return actual == object.BOOLEAN_OBJ
+	case "list":
+		return actual == object.LIST_OBJ
+	case "map":
+		return actual == object.MAP_OBJ
+	case "stream":
+		return actual == object.STREAM_OBJ
+	case "chan":
+		return actual == object.CHAN_OBJ
+	case "num":
+		return actual == object.INTEGER_OBJ || actual == object.FLOAT_OBJ
+	case "fn":
+		return actual == object.FUNCTION_OBJ || actual == object.BUILTIN_OBJ
+	case "any":
+		return actual != object.FAIL_OBJ
+	default:
+		return false
+	}
+}
+
+func extendFunctionMem(fn *object.FunctionObject, args []object.MemoryObject) *object.Memory {
+	return object.NewLocalMemory(fn.Mem, args[:len(fn.Parameters):len(fn.Parameters)])
+}
+
+func isTruthy(obj object.MemoryObject) bool {
+	switch obj {
+	case object.Nil:
+		return false
+	case object.True:
+		return true
+	case object.False:
+		return false
+	default:
+		return true
+	}
+}
+
+func newError(format string, a ...interface{}) *object.ErrorObject {
+	return &object.ErrorObject{Message: fmt.Sprintf(format, a...)}
+}
+
+func isError(obj object.MemoryObject) bool {
+	if obj != nil {
+		return obj.Type() == object.ERROR_OBJ
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"duet/object"
+)
+
+// newFlowBuiltins returns the builtins that branch a pipeline: they hand one value to
+// several functions, so a supplier that feeds more than one consumer runs only once.
+func newFlowBuiltins() map[string]*object.BuiltinObject {
+	return map[string]*object.BuiltinObject{
+		"tee": {
+			HigherOrder: func(call object.Caller, args ...object.MemoryObject) object.MemoryObject {
+				if len(args) < 2 {
+					return newError("wrong number of arguments. got=%d, want at least 2", len(args))
+				}
+				for i, fn := range args[1:] {
+					if !isCallable(fn) {
+						return newError("argument %d to `tee` must be FUNCTION, got %s", i+2, fn.Type())
+					}
+				}
+				for _, fn := range args[1:] {
+					if result := call(fn, args[0]); stopsIteration(result) {
+						return result
+					}
+				}
+				return args[0]
+			},
+		},
+		"fanout": {
+			HigherOrder: func(call object.Caller, args ...object.MemoryObject) object.MemoryObject {
+				if len(args) < 2 {
+					return newError("wrong number of arguments. got=%d, want at least 2", len(args))
+				}
+				value := args[0]
+
+				// fanout(x, {"name": f, ...}) names each branch and returns a map.
+				// Branches run in key order.
+				if branches, ok := args[1].(*object.MapObject); ok && len(args) == 2 {
+					results := object.NewMap()
+					for _, pair := range branches.SortedPairs() {
+						if !isCallable(pair.Value) {
+							return newError("branch %s of `fanout` must be FUNCTION, got %s", pair.Key.Inspect(), pair.Value.Type())
+						}
+						result := call(pair.Value, value)
+						if isError(result) {
+							return result
+						}
+						results = results.Set(pair.Key.(object.Hashable).HashKey(), object.MapPair{Key: pair.Key, Value: result})
+					}
+					return results
+				}
+
+				results := make([]object.MemoryObject, 0, len(args)-1)
+				for i, fn := range args[1:] {
+					if !isCallable(fn) {
+						return newError("argument %d to `fanout` must be FUNCTION or MAP, got %s", i+2, fn.Type())
+					}
+					result := call(fn, value)
+					if isError(result) {
+						return result
+					}
+					results = append(results, result)
+				}
+				return object.NewList(results)
+			},
+		},
+	}
+}
@@ -0,0 +1,253 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"reflect"
+	"strings"
+	"sync"
+
+	"duet/ast"
+	"duet/object"
+)
+
+// CompileOptions controls what happens to a program between parsing and running.
+type CompileOptions struct {
+	Optimize bool      // run the AST optimizer
+	Dump     io.Writer // if set, receives the (optimized) program before it is checked
+	// Globals holds the values a script may use besides its own functions and the
+	// builtins, e.g. functions defined by earlier REPL lines or values set with
+	// SetGlobal. Its names count as defined when the script is checked.
+	Globals *object.Memory
+	// Path is the file the script was read from, if any. Imports are looked up
+	// relative to its directory first, then in each directory of ModulePath.
+	Path       string
+	ModulePath []string
+	// FS reads imported modules other than the standard library. If nil, they
+	// are read from the OS file system. Set it to the engine's *object.IO to
+	// apply its Sandbox to imports as well.
+	FS fs.FS
+}
+
+// Script is a program that has been parsed, optimized and checked once. It can be
+// run many times, by one engine or by several engines at the same time.
+type Script struct {
+	Program *ast.Program
+
+	once sync.Once
+	code *CompiledFunction // bytecode for engines that use the VM, compiled on first use
+	err  error
+}
+
+// ParseError lists the syntax errors of a script.
+type ParseError struct {
+	Errors []string
+}
+
+func (e *ParseError) Error() string {
+	return strings.Join(e.Errors, "\n")
+}
+
+// CompileScript parses source, compiles the modules it imports, optimizes it if
+// asked to, resolves its names and checks its effect rules. A check failure, or a
+// module that cannot be found or imports itself, is returned as an
+// *object.ErrorObject; errors in a module are prefixed with its path.
+func CompileScript(source string, options CompileOptions) (*Script, error) {
+	globals := options.Globals
+	if globals == nil {
+		globals = object.NewMemory()
+	}
+	return newLoader(options).compile(source, options.Path, globals, options.Dump)
+}
+
+func (s *Script) compiled() (*CompiledFunction, error) {
+	s.once.Do(func() {
+		s.code, s.err = Compile(s.Program)
+	})
+	return s.code, s.err
+}
+
+// New creates an engine with empty globals, for use with Exec and Call.
+func New(options EngineOptions) *ExcutionEngine {
+	e := NewExcutionEngine(nil, nil)
+	e.Options = options
+	return e
+}
+
+// SetGlobal converts value with object.FromGo and binds it to name, so that
+// scripts compiled against e.Memory can refer to it.
+func (e *ExcutionEngine) SetGlobal(name string, value any) error {
+	obj, err := object.FromGo(value)
+	if err != nil {
+		return err
+	}
+	e.Memory.Set(name, obj)
+	return nil
+}
+
+// Exec runs script in e's globals and returns the value of its last statement,
+// which is nil when that statement defines a function. The functions the script
+// defines stay in e's globals for later scripts and for Call. Errors, including
+// exceeded budgets, are returned as an *object.ErrorObject.
+func (e *ExcutionEngine) Exec(ctx context.Context, script *Script) (object.MemoryObject, error) {
+	return hostResult(e.run(ctx, func() object.MemoryObject {
+		if !e.Options.UseVM {
+			return e.Eval(script.Program, e.Memory)
+		}
+		main, err := script.compiled()
+		if err != nil {
+			return newError("%s", err)
+		}
+		return NewVM(e, e.Memory).Run(main)
+	}))
+}
+
+// Call calls the function bound to name, usually one defined by a script run with
+// Exec, with args converted by object.FromGo. A function exported by a module the
+// script imported is named like in Duet, e.g. "text.normalize".
+func (e *ExcutionEngine) Call(ctx context.Context, name string, args ...any) (object.MemoryObject, error) {
+	var fn object.MemoryObject
+	if alias, memberName, qualified := strings.Cut(name, "."); qualified {
+		module, ok := e.Memory.Get(alias)
+		if !ok {
+			return nil, newError("identifier not found: %s", alias)
+		}
+		if fn = member(module, alias, memberName); isError(fn) {
+			return nil, fn.(*object.ErrorObject)
+		}
+	} else if value, ok := e.Memory.Get(name); ok {
+		fn = value
+	} else if fn, ok = builtins[name]; !ok {
+		return nil, newError("identifier not found: %s", name)
+	}
+	values := make([]object.MemoryObject, len(args))
+	for i, arg := range args {
+		value, err := object.FromGo(arg)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return hostResult(e.run(ctx, func() object.MemoryObject {
+		return e.caller()(fn, values...)
+	}))
+}
+
+// Register binds the Go function fn to name in e's globals, so scripts compiled
+// against e.Memory can call it like a builtin. Arguments are converted with
+// object.ToGoType and results with object.FromGo; a non-nil error result becomes a
+// FAIL. fn may take a context.Context first, which receives the context of the
+// run, and may be variadic. It may return nothing, a value, an error, or a value
+// and an error. Registered functions are treated as effectful, so procs cannot
+// call them; use RegisterPure for functions without side effects.
+func (e *ExcutionEngine) Register(name string, fn any) error {
+	return e.register(name, fn, true)
+}
+
+// RegisterPure is like Register for a function whose result depends only on its
+// arguments, which procs and @memo procs may call.
+func (e *ExcutionEngine) RegisterPure(name string, fn any) error {
+	return e.register(name, fn, false)
+}
+
+var (
+	contextType = reflect.TypeFor[context.Context]()
+	errorType   = reflect.TypeFor[error]()
+)
+
+func (e *ExcutionEngine) register(name string, fn any, effectful bool) error {
+	v := reflect.ValueOf(fn)
+	if !v.IsValid() {
+		return fmt.Errorf("host function %s must be a func, got nil", name)
+	}
+	t := v.Type()
+	if t.Kind() != reflect.Func {
+		return fmt.Errorf("host function %s must be a func, got %s", name, t)
+	}
+	if v.IsNil() {
+		return fmt.Errorf("host function %s is a nil %s", name, t)
+	}
+	takesContext := t.NumIn() > 0 && t.In(0) == contextType
+	params := t.NumIn()
+	if takesContext {
+		params--
+	}
+	returnsError := t.NumOut() > 0 && t.Out(t.NumOut()-1) == errorType
+	switch {
+	case t.NumOut() > 2, t.NumOut() == 2 && !returnsError:
+		return fmt.Errorf("host function %s must return at most a value and an error", name)
+	}
+
+	call := func(args ...object.MemoryObject) object.MemoryObject {
+		if t.IsVariadic() && len(args) < params-1 {
+			return newError("wrong number of arguments. got=%d, want at least %d", len(args), params-1)
+		}
+		if !t.IsVariadic() && len(args) != params {
+			return newError("wrong number of arguments. got=%d, want=%d", len(args), params)
+		}
+
+		in := make([]reflect.Value, 0, t.NumIn())
+		if takesContext {
+			ctx := e.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			in = append(in, reflect.ValueOf(ctx))
+		}
+		for i, arg := range args {
+			var pt reflect.Type
+			if last := t.NumIn() - 1; t.IsVariadic() && len(in) >= last {
+				pt = t.In(last).Elem()
+			} else {
+				pt = t.In(len(in))
+			}
+			value, err := object.ToGoType(arg, pt)
+			if err != nil {
+				return newError("argument %d to `%s` %s", i+1, name, err)
+			}
+			in = append(in, value)
+		}
+
+		out, err := callHost(v, in)
+		if err != nil {
+			return newError("host function %s panicked: %v", name, err)
+		}
+		if returnsError {
+			if err, _ := out[len(out)-1].Interface().(error); err != nil {
+				return &object.FailObject{Message: err.Error()}
+			}
+			out = out[:len(out)-1]
+		}
+		if len(out) == 0 {
+			return object.Nil
+		}
+		result, err := object.FromGo(out[0].Interface())
+		if err != nil {
+			return newError("result of `%s`: %s", name, err)
+		}
+		return result
+	}
+
+	e.Memory.Set(name, &object.BuiltinObject{Fn: call, Effectful: effectful})
+	return nil
+}
+
+// callHost calls a host function, turning a panic into an error.
+func callHost(fn reflect.Value, in []reflect.Value) (out []reflect.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return fn.Call(in), nil
+}
+
+// hostResult turns an ERROR result into a Go error.
+func hostResult(result object.MemoryObject) (object.MemoryObject, error) {
+	if err, ok := result.(*object.ErrorObject); ok {
+		return nil, err
+	}
+	return result, nil
+}
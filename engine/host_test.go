@@ -0,0 +1,32 @@
+package engine
+
+import (
+	"testing"
+
+	"duet/object"
+)
+
+func TestRegisterRejectsNonFuncs(t *testing.T) {
+	var nilFunc func(int) int
+	tests := []struct {
+		fn   any
+		want string
+	}{
+		{nil, "host function f must be a func, got nil"},
+		{42, "host function f must be a func, got int"},
+		{nilFunc, "host function f is a nil func(int) int"},
+		{func() (int, int) { return 0, 0 }, "host function f must return at most a value and an error"},
+	}
+	for _, tt := range tests {
+		e := New(EngineOptions{IO: &object.IO{}})
+		for _, register := range []func(string, any) error{e.Register, e.RegisterPure} {
+			err := register("f", tt.fn)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("register(%#v) = %v, want %q", tt.fn, err, tt.want)
+			}
+		}
+		if _, ok := e.Memory.Get("f"); ok {
+			t.Errorf("register(%#v) bound f", tt.fn)
+		}
+	}
+}
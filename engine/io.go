@@ -0,0 +1,164 @@
+package engine
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"strings"
+
+	"duet/object"
+)
+
+// stdIO는 Options.IO를 지정하지 않은 엔진들이 함께 쓰는 프로세스의 입출력입니다.
+// 하나를 공유하므로 readln이 미리 읽어 둔 입력이 엔진 사이에서 사라지지 않습니다.
+var stdIO = object.StdIO()
+
+// io는 입출력 빌트인에 전달할 엔진의 입출력을 반환합니다.
+func (e *ExcutionEngine) io() *object.IO {
+	if e.Options.IO != nil {
+		return e.Options.IO
+	}
+	return stdIO
+}
+
+// ioFail은 입출력 에러를 FAIL로 바꿉니다. 샌드박스가 막은 경우에는 Code가 PERMISSION_DENIED입니다.
+func ioFail(what string, err error) *object.FailObject {
+	fail := object.NewFail("could not %s: %s", what, err)
+	if errors.Is(err, object.ErrDenied) {
+		fail.Code = object.PERMISSION_DENIED
+	}
+	return fail
+}
+
+func newIOBuiltins() map[string]*object.BuiltinObject {
+	return map[string]*object.BuiltinObject{
+		"print": {
+			Effectful: true,
+			IO: func(sys *object.IO, args ...object.MemoryObject) object.MemoryObject {
+				for _, arg := range args {
+					if err := sys.WriteLine(false, arg.Inspect()); err != nil {
+						return ioFail("print", err)
+					}
+				}
+				return object.Nil
+			},
+		},
+		"eprint": {
+			Effectful: true,
+			IO: func(sys *object.IO, args ...object.MemoryObject) object.MemoryObject {
+				for _, arg := range args {
+					if err := sys.WriteLine(true, arg.Inspect()); err != nil {
+						return ioFail("print", err)
+					}
+				}
+				return object.Nil
+			},
+		},
+		"readln": {
+			Effectful: true,
+			IO: func(sys *object.IO, args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 0 {
+					return object.NewFail("wrong number of arguments. got=%d, want=0", len(args))
+				}
+				text, err := sys.ReadLine()
+				if errors.Is(err, object.ErrUnavailable) || errors.Is(err, object.ErrDenied) {
+					return ioFail("read stdin", err)
+				}
+				return &object.StringObject{Value: strings.TrimSpace(text)}
+			},
+		},
+		"read": {
+			Effectful: true,
+			IO: func(sys *object.IO, args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 1 {
+					return object.NewFail("wrong number of arguments. got=%d, want=1", len(args))
+				}
+				path, ok := args[0].(*object.StringObject)
+				if !ok {
+					return object.NewFail("argument to `read` must be STRING, got %s", args[0].Type())
+				}
+				data, err := sys.ReadFile(path.Value)
+				if err != nil {
+					return ioFail("read file", err)
+				}
+				return &object.StringObject{Value: string(data)}
+			},
+		},
+		"write": {
+			Effectful: true,
+			IO: func(sys *object.IO, args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 2 {
+					return object.NewFail("wrong number of arguments. got=%d, want=2", len(args))
+				}
+				path, ok := args[0].(*object.StringObject)
+				if !ok {
+					return object.NewFail("first argument to `write` must be STRING, got %s", args[0].Type())
+				}
+				content, ok := args[1].(*object.StringObject)
+				if !ok {
+					return object.NewFail("second argument to `write` must be STRING, got %s", args[1].Type())
+				}
+				err := sys.WriteFile(path.Value, []byte(content.Value))
+				if err != nil {
+					return ioFail("write file", err)
+				}
+				return object.True
+			},
+		},
+		"lines": {
+			Effectful: true,
+			IO: func(sys *object.IO, args ...object.MemoryObject) object.MemoryObject {
+				file, fail := openLines("lines", sys, args)
+				if fail != nil {
+					return fail
+				}
+				defer file.Close()
+				var elements []object.MemoryObject
+				scanner := bufio.NewScanner(file)
+				for scanner.Scan() {
+					elements = append(elements, &object.StringObject{Value: scanner.Text()})
+				}
+				if err := scanner.Err(); err != nil {
+					return ioFail("read file", err)
+				}
+				return object.NewList(elements)
+			},
+		},
+		"stream_lines": {
+			Effectful: true,
+			IO: func(sys *object.IO, args ...object.MemoryObject) object.MemoryObject {
+				file, fail := openLines("stream_lines", sys, args)
+				if fail != nil {
+					return fail
+				}
+				// 파일을 한 번에 읽지 않고, 줄을 요청받을 때마다 하나씩 읽습니다.
+				scanner := bufio.NewScanner(file)
+				return object.NewStream(func() (object.MemoryObject, bool) {
+					if !scanner.Scan() {
+						if err := scanner.Err(); err != nil {
+							return newError("could not read file: %s", err), true
+						}
+						return nil, false
+					}
+					return &object.StringObject{Value: scanner.Text()}, true
+				}, func() { file.Close() })
+			},
+		},
+	}
+}
+
+// openLines는 lines와 stream_lines가 읽을 파일을 엽니다.
+func openLines(name string, sys *object.IO, args []object.MemoryObject) (fs.File, *object.FailObject) {
+	if len(args) != 1 {
+		return nil, object.NewFail("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	path, ok := args[0].(*object.StringObject)
+	if !ok {
+		return nil, object.NewFail("argument to `%s` must be STRING, got %s", name, args[0].Type())
+	}
+	file, err := sys.Open(path.Value)
+	if err != nil {
+		return nil, ioFail("open file", err)
+	}
+	return file, nil
+}
@@ -0,0 +1,221 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"duet/object"
+)
+
+func newJSONBuiltins() map[string]*object.BuiltinObject {
+	return map[string]*object.BuiltinObject{
+		"json_parse": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got=%d, want=1", len(args))
+				}
+				s, ok := args[0].(*object.StringObject)
+				if !ok {
+					return newError("argument to `json_parse` must be STRING, got %s", args[0].Type())
+				}
+				return parseJSON(s.Value)
+			},
+		},
+		"json_stringify": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) < 1 || len(args) > 2 {
+					return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+				}
+				indent := 0
+				if len(args) == 2 {
+					n, ok := args[1].(*object.IntegerObject)
+					if !ok {
+						return newError("second argument to `json_stringify` must be INTEGER, got %s", args[1].Type())
+					}
+					indent = int(n.Value)
+				}
+				return stringifyJSON(args[0], indent)
+			},
+		},
+	}
+}
+
+// parseJSON decodes a JSON document. Numbers without a fraction or exponent become
+// integers, null becomes nil and objects become maps. Malformed input gives a FAIL
+// that says where the problem is.
+func parseJSON(s string) object.MemoryObject {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		var syntax *json.SyntaxError
+		switch {
+		case errors.As(err, &syntax):
+			return jsonFail(s, int(syntax.Offset), syntax.Error())
+		case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+			return jsonFail(s, len(s), "unexpected end of JSON input")
+		}
+		return jsonFail(s, int(dec.InputOffset()), err.Error())
+	}
+	if rest := strings.TrimLeft(s[dec.InputOffset():], " \t\r\n"); rest != "" {
+		r, _ := utf8.DecodeRuneInString(rest)
+		return jsonFail(s, len(s)-len(rest)+1, "invalid character "+strconv.QuoteRune(r)+" after top-level value")
+	}
+	obj, err := fromJSON(v)
+	if err != nil {
+		return object.NewFail("could not parse JSON: %s", err)
+	}
+	return obj
+}
+
+// jsonFail reports a parse error found after reading offset bytes of s, with
+// the 1-based line and column of the last byte read.
+func jsonFail(s string, offset int, msg string) *object.FailObject {
+	offset = min(offset, len(s))
+	before := s[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := utf8.RuneCountInString(before[strings.LastIndexByte(before, '\n')+1:])
+	return object.NewFail("could not parse JSON at line %d, column %d: %s", line, max(column, 1), msg)
+}
+
+// fromJSON converts a decoded JSON value. Numbers too large for a float, which
+// would become infinities that cannot be written back, give an error.
+func fromJSON(v any) (object.MemoryObject, error) {
+	switch v := v.(type) {
+	case nil:
+		return object.Nil, nil
+	case bool:
+		return nativeBoolToBooleanObject(v), nil
+	case string:
+		return &object.StringObject{Value: v}, nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return &object.IntegerObject{Value: i}, nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, errors.New("number " + v.String() + " is out of range")
+		}
+		return &object.FloatObject{Value: f}, nil
+	case []any:
+		elements := make([]object.MemoryObject, len(v))
+		for i, el := range v {
+			obj, err := fromJSON(el)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = obj
+		}
+		return object.NewList(elements), nil
+	case map[string]any:
+		m := object.NewMap()
+		for k, el := range v {
+			obj, err := fromJSON(el)
+			if err != nil {
+				return nil, err
+			}
+			key := &object.StringObject{Value: k}
+			m = m.Set(key.HashKey(), object.MapPair{Key: key, Value: obj})
+		}
+		return m, nil
+	}
+	return object.Nil, nil
+}
+
+// stringifyJSON encodes a value as JSON, indenting nested values by indent spaces
+// or, if indent is 0, writing it on one line. Map keys are written in key order;
+// integer and boolean keys become strings, and two keys that become the same
+// string, such as 1 and "1", give a FAIL. Values with no JSON form, such as
+// functions and FAILs inside a list, give a FAIL too.
+func stringifyJSON(obj object.MemoryObject, indent int) object.MemoryObject {
+	v, err := toJSON(obj)
+	if err != nil {
+		return object.NewFail("could not convert to JSON: %s", err)
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if indent > 0 {
+		enc.SetIndent("", strings.Repeat(" ", indent))
+	}
+	if err := enc.Encode(v); err != nil {
+		return object.NewFail("could not convert to JSON: %s", err)
+	}
+	return &object.StringObject{Value: strings.TrimSuffix(buf.String(), "\n")}
+}
+
+func toJSON(obj object.MemoryObject) (any, error) {
+	switch obj := obj.(type) {
+	case *object.NilObject:
+		return nil, nil
+	case *object.BooleanObject:
+		return obj.Value, nil
+	case *object.StringObject:
+		return obj.Value, nil
+	case *object.IntegerObject:
+		return obj.Value, nil
+	case *object.FloatObject:
+		return jsonFloat(obj.Value)
+	case *object.ListObject:
+		elements := make([]any, 0, obj.Len())
+		for _, el := range obj.All() {
+			v, err := toJSON(el)
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, v)
+		}
+		return elements, nil
+	case *object.MapObject:
+		fields := make(map[string]any, obj.Len())
+		keys := make(map[string]object.MemoryObject, obj.Len())
+		for _, pair := range obj.SortedPairs() {
+			var key string
+			switch k := pair.Key.(type) {
+			case *object.StringObject:
+				key = k.Value
+			case *object.IntegerObject, *object.BooleanObject:
+				key = k.Inspect()
+			default:
+				return nil, errors.New("map keys must be STRING, INTEGER or BOOLEAN, got " + string(pair.Key.Type()))
+			}
+			if other, ok := keys[key]; ok {
+				return nil, fmt.Errorf("map keys %s (%s) and %s (%s) are both written as %q", other.Inspect(), other.Type(), pair.Key.Inspect(), pair.Key.Type(), key)
+			}
+			keys[key] = pair.Key
+			v, err := toJSON(pair.Value)
+			if err != nil {
+				return nil, err
+			}
+			fields[key] = v
+		}
+		return fields, nil
+	case *object.FailObject:
+		return nil, errors.New("FAIL " + strconv.Quote(obj.Message) + " has no JSON form")
+	}
+	return nil, errors.New(string(obj.Type()) + " has no JSON form")
+}
+
+// jsonFloat keeps a float a float when it is parsed back by writing integral
+// values with ".0", e.g. 2.0 rather than 2.
+func jsonFloat(f float64) (json.Number, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", errors.New(strconv.FormatFloat(f, 'g', -1, 64) + " has no JSON form")
+	}
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	s := strconv.FormatFloat(f, format, -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return json.Number(s), nil
+}
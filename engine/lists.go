@@ -0,0 +1,483 @@
+package engine
+
+import (
+	"math"
+	"sort"
+
+	"duet/object"
+)
+
+func isCallable(obj object.MemoryObject) bool {
+	switch obj.Type() {
+	case object.FUNCTION_OBJ, object.BUILTIN_OBJ:
+		return true
+	default:
+		return false
+	}
+}
+
+// objectKey returns a key identifying a value by content, for hashable and unhashable values alike.
+func objectKey(obj object.MemoryObject) string {
+	if h, ok := obj.(object.Hashable); ok {
+		return h.HashKey()
+	}
+	return string(obj.Type()) + ":" + obj.Inspect()
+}
+
+// listAndFunction validates the common (list, fn) argument shape of higher-order builtins.
+func listAndFunction(name string, args []object.MemoryObject) (*object.ListObject, object.MemoryObject, *object.ErrorObject) {
+	if len(args) != 2 {
+		return nil, nil, newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	list, ok := args[0].(*object.ListObject)
+	if !ok {
+		return nil, nil, newError("first argument to `%s` must be LIST, got %s", name, args[0].Type())
+	}
+	if !isCallable(args[1]) {
+		return nil, nil, newError("second argument to `%s` must be FUNCTION, got %s", name, args[1].Type())
+	}
+	return list, args[1], nil
+}
+
+// stopsIteration reports whether a callback result must abort a higher-order builtin.
+func stopsIteration(obj object.MemoryObject) bool {
+	return isError(obj) || (obj != nil && obj.Type() == object.FAIL_OBJ)
+}
+
+func newListBuiltins() map[string]*object.BuiltinObject {
+	return map[string]*object.BuiltinObject{
+		"len": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 1 {
+					return object.NewFail("wrong number of arguments. got=%d, want=1", len(args))
+				}
+				switch arg := args[0].(type) {
+				case *object.StringObject:
+					return &object.IntegerObject{Value: int64(len(arg.Value))}
+				case *object.ListObject:
+					return &object.IntegerObject{Value: int64(arg.Len())}
+				case *object.MapObject:
+					return &object.IntegerObject{Value: int64(arg.Len())}
+				default:
+					return object.NewFail("argument to `len` not supported, got %s", args[0].Type())
+				}
+			},
+		},
+		"first": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got=%d, want=1", len(args))
+				}
+				if args[0].Type() != object.LIST_OBJ {
+					return newError("argument to `first` must be LIST, got %s", args[0].Type())
+				}
+				list := args[0].(*object.ListObject)
+				if list.Len() > 0 {
+					return list.At(0)
+				}
+				return object.Nil
+			},
+		},
+		"last": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got=%d, want=1", len(args))
+				}
+				if args[0].Type() != object.LIST_OBJ {
+					return newError("argument to `last` must be LIST, got %s", args[0].Type())
+				}
+				list := args[0].(*object.ListObject)
+				length := list.Len()
+				if length > 0 {
+					return list.At(length - 1)
+				}
+				return object.Nil
+			},
+		},
+		"rest": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got=%d, want=1", len(args))
+				}
+				if args[0].Type() != object.LIST_OBJ {
+					return newError("argument to `rest` must be LIST, got %s", args[0].Type())
+				}
+				list := args[0].(*object.ListObject)
+				length := list.Len()
+				if length > 0 {
+					return list.Slice(1, length)
+				}
+				return object.Nil
+			},
+		},
+		"push": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 2 {
+					return newError("wrong number of arguments. got=%d, want=2", len(args))
+				}
+				if args[0].Type() != object.LIST_OBJ {
+					return newError("argument to `push` must be LIST, got %s", args[0].Type())
+				}
+				list := args[0].(*object.ListObject)
+				return list.Push(args[1])
+			},
+		},
+		"map": {
+			HigherOrder: func(call object.Caller, args ...object.MemoryObject) object.MemoryObject {
+				list, fn, err := listAndFunction("map", args)
+				if err != nil {
+					return err
+				}
+				elements := make([]object.MemoryObject, list.Len())
+				for i, el := range list.All() {
+					result := call(fn, el)
+					if isError(result) {
+						return result
+					}
+					elements[i] = result
+				}
+				return object.NewList(elements)
+			},
+		},
+		"filter": {
+			HigherOrder: func(call object.Caller, args ...object.MemoryObject) object.MemoryObject {
+				list, fn, err := listAndFunction("filter", args)
+				if err != nil {
+					return err
+				}
+				elements := []object.MemoryObject{}
+				for _, el := range list.All() {
+					result := call(fn, el)
+					if stopsIteration(result) {
+						return result
+					}
+					if isTruthy(result) {
+						elements = append(elements, el)
+					}
+				}
+				return object.NewList(elements)
+			},
+		},
+		"reduce": {
+			HigherOrder: func(call object.Caller, args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 3 {
+					return newError("wrong number of arguments. got=%d, want=3", len(args))
+				}
+				list, fn, err := listAndFunction("reduce", args[:2])
+				if err != nil {
+					return err
+				}
+				acc := args[2]
+				for _, el := range list.All() {
+					acc = call(fn, acc, el)
+					if stopsIteration(acc) {
+						return acc
+					}
+				}
+				return acc
+			},
+		},
+		"sort": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got=%d, want=1", len(args))
+				}
+				list, ok := args[0].(*object.ListObject)
+				if !ok {
+					return newError("argument to `sort` must be LIST, got %s", args[0].Type())
+				}
+				elements := list.Elements()
+				sort.SliceStable(elements, func(i, j int) bool {
+					return object.CompareObjects(elements[i], elements[j]) < 0
+				})
+				return object.NewList(elements)
+			},
+		},
+		"sort_by": {
+			HigherOrder: func(call object.Caller, args ...object.MemoryObject) object.MemoryObject {
+				list, fn, err := listAndFunction("sort_by", args)
+				if err != nil {
+					return err
+				}
+				keys := make([]object.MemoryObject, list.Len())
+				for i, el := range list.All() {
+					key := call(fn, el)
+					if stopsIteration(key) {
+						return key
+					}
+					keys[i] = key
+				}
+				indexes := make([]int, list.Len())
+				for i := range indexes {
+					indexes[i] = i
+				}
+				sort.SliceStable(indexes, func(i, j int) bool {
+					return object.CompareObjects(keys[indexes[i]], keys[indexes[j]]) < 0
+				})
+				elements := make([]object.MemoryObject, len(indexes))
+				for i, idx := range indexes {
+					elements[i] = list.At(idx)
+				}
+				return object.NewList(elements)
+			},
+		},
+		"group_by": {
+			HigherOrder: func(call object.Caller, args ...object.MemoryObject) object.MemoryObject {
+				list, fn, err := listAndFunction("group_by", args)
+				if err != nil {
+					return err
+				}
+				groups := object.NewMap()
+				for _, el := range list.All() {
+					key := call(fn, el)
+					if stopsIteration(key) {
+						return key
+					}
+					hashKey, ok := key.(object.Hashable)
+					if !ok {
+						return newError("unusable as hash key: %s", key.Type())
+					}
+					hashed := hashKey.HashKey()
+					group, ok := groups.Get(hashed)
+					if !ok {
+						group = object.MapPair{Key: key, Value: object.NewList(nil)}
+					}
+					group.Value = group.Value.(*object.ListObject).Push(el)
+					groups = groups.Set(hashed, group)
+				}
+				return groups
+			},
+		},
+		"zip": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 2 {
+					return newError("wrong number of arguments. got=%d, want=2", len(args))
+				}
+				a, ok := args[0].(*object.ListObject)
+				if !ok {
+					return newError("first argument to `zip` must be LIST, got %s", args[0].Type())
+				}
+				b, ok := args[1].(*object.ListObject)
+				if !ok {
+					return newError("second argument to `zip` must be LIST, got %s", args[1].Type())
+				}
+				length := min(a.Len(), b.Len())
+				elements := make([]object.MemoryObject, length)
+				for i := 0; i < length; i++ {
+					elements[i] = object.NewList([]object.MemoryObject{a.At(i), b.At(i)})
+				}
+				return object.NewList(elements)
+			},
+		},
+		"enumerate": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got=%d, want=1", len(args))
+				}
+				list, ok := args[0].(*object.ListObject)
+				if !ok {
+					return newError("argument to `enumerate` must be LIST, got %s", args[0].Type())
+				}
+				elements := make([]object.MemoryObject, list.Len())
+				for i, el := range list.All() {
+					elements[i] = object.NewList([]object.MemoryObject{&object.IntegerObject{Value: int64(i)}, el})
+				}
+				return object.NewList(elements)
+			},
+		},
+		"flatten": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got=%d, want=1", len(args))
+				}
+				list, ok := args[0].(*object.ListObject)
+				if !ok {
+					return newError("argument to `flatten` must be LIST, got %s", args[0].Type())
+				}
+				elements := []object.MemoryObject{}
+				for _, el := range list.All() {
+					if inner, ok := el.(*object.ListObject); ok {
+						elements = append(elements, inner.Elements()...)
+					} else {
+						elements = append(elements, el)
+					}
+				}
+				return object.NewList(elements)
+			},
+		},
+		"unique": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got=%d, want=1", len(args))
+				}
+				list, ok := args[0].(*object.ListObject)
+				if !ok {
+					return newError("argument to `unique` must be LIST, got %s", args[0].Type())
+				}
+				seen := make(map[string]bool)
+				elements := []object.MemoryObject{}
+				for _, el := range list.All() {
+					key := objectKey(el)
+					if seen[key] {
+						continue
+					}
+					seen[key] = true
+					elements = append(elements, el)
+				}
+				return object.NewList(elements)
+			},
+		},
+		"take": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 2 {
+					return newError("wrong number of arguments. got=%d, want=2", len(args))
+				}
+				list, ok := args[0].(*object.ListObject)
+				if !ok {
+					return newError("first argument to `take` must be LIST, got %s", args[0].Type())
+				}
+				n, ok := args[1].(*object.IntegerObject)
+				if !ok {
+					return newError("second argument to `take` must be INTEGER, got %s", args[1].Type())
+				}
+				count := max(0, min(int(n.Value), list.Len()))
+				return list.Slice(0, count)
+			},
+		},
+		"drop": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 2 {
+					return newError("wrong number of arguments. got=%d, want=2", len(args))
+				}
+				list, ok := args[0].(*object.ListObject)
+				if !ok {
+					return newError("first argument to `drop` must be LIST, got %s", args[0].Type())
+				}
+				n, ok := args[1].(*object.IntegerObject)
+				if !ok {
+					return newError("second argument to `drop` must be INTEGER, got %s", args[1].Type())
+				}
+				start := max(0, min(int(n.Value), list.Len()))
+				return list.Slice(start, list.Len())
+			},
+		},
+		"any": {
+			HigherOrder: func(call object.Caller, args ...object.MemoryObject) object.MemoryObject {
+				list, fn, err := listAndFunction("any", args)
+				if err != nil {
+					return err
+				}
+				for _, el := range list.All() {
+					result := call(fn, el)
+					if stopsIteration(result) {
+						return result
+					}
+					if isTruthy(result) {
+						return object.True
+					}
+				}
+				return object.False
+			},
+		},
+		"all": {
+			HigherOrder: func(call object.Caller, args ...object.MemoryObject) object.MemoryObject {
+				list, fn, err := listAndFunction("all", args)
+				if err != nil {
+					return err
+				}
+				for _, el := range list.All() {
+					result := call(fn, el)
+					if stopsIteration(result) {
+						return result
+					}
+					if !isTruthy(result) {
+						return object.False
+					}
+				}
+				return object.True
+			},
+		},
+		"find": {
+			HigherOrder: func(call object.Caller, args ...object.MemoryObject) object.MemoryObject {
+				list, fn, err := listAndFunction("find", args)
+				if err != nil {
+					return err
+				}
+				for _, el := range list.All() {
+					result := call(fn, el)
+					if stopsIteration(result) {
+						return result
+					}
+					if isTruthy(result) {
+						return el
+					}
+				}
+				return object.Nil
+			},
+		},
+		"range": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				start, end, step, err := rangeBounds(args)
+				if err != nil {
+					return err
+				}
+				// Counting the elements first keeps i += step from overflowing past end
+				// near the int64 limits; the last increment may wrap but is never used.
+				elements := []object.MemoryObject{}
+				i := start
+				for range rangeLen(start, end, step) {
+					elements = append(elements, &object.IntegerObject{Value: i})
+					i += step
+				}
+				return object.NewList(elements)
+			},
+			Size: func(args ...object.MemoryObject) int {
+				start, end, step, err := rangeBounds(args)
+				if err != nil {
+					return 0
+				}
+				return rangeLen(start, end, step)
+			},
+		},
+	}
+}
+
+// rangeBounds returns the start, end and step given to `range`.
+func rangeBounds(args []object.MemoryObject) (start, end, step int64, err *object.ErrorObject) {
+	if len(args) < 1 || len(args) > 3 {
+		return 0, 0, 0, newError("wrong number of arguments. got=%d, want=1..3", len(args))
+	}
+	bounds := make([]int64, len(args))
+	for i, arg := range args {
+		n, ok := arg.(*object.IntegerObject)
+		if !ok {
+			return 0, 0, 0, newError("arguments to `range` must be INTEGER, got %s", arg.Type())
+		}
+		bounds[i] = n.Value
+	}
+	start, end, step = 0, bounds[0], 1
+	if len(bounds) >= 2 {
+		start, end = bounds[0], bounds[1]
+	}
+	if len(bounds) == 3 {
+		step = bounds[2]
+	}
+	if step == 0 {
+		return 0, 0, 0, newError("step for `range` must not be zero")
+	}
+	return start, end, step, nil
+}
+
+// rangeLen returns the number of elements `range` produces, or math.MaxInt if it
+// does not fit in an int. The differences are computed in uint64, where they
+// cannot overflow.
+func rangeLen(start, end, step int64) int {
+	var n uint64
+	switch {
+	case step > 0 && start < end:
+		n = (uint64(end)-uint64(start)-1)/uint64(step) + 1
+	case step < 0 && start > end:
+		n = (uint64(start)-uint64(end)-1)/(-uint64(step)) + 1
+	}
+	return int(min(n, math.MaxInt))
+}
@@ -0,0 +1,157 @@
+package engine
+
+import (
+	"duet/object"
+)
+
+func newMapBuiltins() map[string]*object.BuiltinObject {
+	return map[string]*object.BuiltinObject{
+		"keys": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got=%d, want=1", len(args))
+				}
+				m, ok := args[0].(*object.MapObject)
+				if !ok {
+					return newError("argument to `keys` must be MAP, got %s", args[0].Type())
+				}
+				pairs := m.SortedPairs()
+				elements := make([]object.MemoryObject, len(pairs))
+				for i, pair := range pairs {
+					elements[i] = pair.Key
+				}
+				return object.NewList(elements)
+			},
+		},
+		"values": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got=%d, want=1", len(args))
+				}
+				m, ok := args[0].(*object.MapObject)
+				if !ok {
+					return newError("argument to `values` must be MAP, got %s", args[0].Type())
+				}
+				pairs := m.SortedPairs()
+				elements := make([]object.MemoryObject, len(pairs))
+				for i, pair := range pairs {
+					elements[i] = pair.Value
+				}
+				return object.NewList(elements)
+			},
+		},
+		"entries": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got=%d, want=1", len(args))
+				}
+				m, ok := args[0].(*object.MapObject)
+				if !ok {
+					return newError("argument to `entries` must be MAP, got %s", args[0].Type())
+				}
+				pairs := m.SortedPairs()
+				elements := make([]object.MemoryObject, len(pairs))
+				for i, pair := range pairs {
+					elements[i] = object.NewList([]object.MemoryObject{pair.Key, pair.Value})
+				}
+				return object.NewList(elements)
+			},
+		},
+		"has": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 2 {
+					return newError("wrong number of arguments. got=%d, want=2", len(args))
+				}
+				m, ok := args[0].(*object.MapObject)
+				if !ok {
+					return newError("first argument to `has` must be MAP, got %s", args[0].Type())
+				}
+				key, ok := args[1].(object.Hashable)
+				if !ok {
+					return newError("unusable as hash key: %s", args[1].Type())
+				}
+				if _, ok := m.Get(key.HashKey()); ok {
+					return object.True
+				}
+				return object.False
+			},
+		},
+		"put": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 3 {
+					return newError("wrong number of arguments. got=%d, want=3", len(args))
+				}
+				m, ok := args[0].(*object.MapObject)
+				if !ok {
+					return newError("first argument to `put` must be MAP, got %s", args[0].Type())
+				}
+				key, ok := args[1].(object.Hashable)
+				if !ok {
+					return newError("unusable as hash key: %s", args[1].Type())
+				}
+				return m.Set(key.HashKey(), object.MapPair{Key: args[1], Value: args[2]})
+			},
+		},
+		"remove": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 2 {
+					return newError("wrong number of arguments. got=%d, want=2", len(args))
+				}
+				m, ok := args[0].(*object.MapObject)
+				if !ok {
+					return newError("first argument to `remove` must be MAP, got %s", args[0].Type())
+				}
+				key, ok := args[1].(object.Hashable)
+				if !ok {
+					return newError("unusable as hash key: %s", args[1].Type())
+				}
+				return m.Delete(key.HashKey())
+			},
+		},
+		"merge": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) < 2 {
+					return newError("wrong number of arguments. got=%d, want at least 2", len(args))
+				}
+				merged, ok := args[0].(*object.MapObject)
+				if !ok {
+					return newError("argument 1 to `merge` must be MAP, got %s", args[0].Type())
+				}
+				for i, arg := range args[1:] {
+					m, ok := arg.(*object.MapObject)
+					if !ok {
+						return newError("argument %d to `merge` must be MAP, got %s", i+2, arg.Type())
+					}
+					for hashed, pair := range m.All() {
+						merged = merged.Set(hashed, pair)
+					}
+				}
+				return merged
+			},
+		},
+		"map_from": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got=%d, want=1", len(args))
+				}
+				list, ok := args[0].(*object.ListObject)
+				if !ok {
+					return newError("argument to `map_from` must be LIST, got %s", args[0].Type())
+				}
+				pairs := object.NewMap()
+				for _, el := range list.All() {
+					pair, ok := el.(*object.ListObject)
+					if !ok || pair.Len() != 2 {
+						return newError("elements of `map_from` must be [key, value] pairs, got %s", el.Inspect())
+					}
+					key, ok := pair.At(0).(object.Hashable)
+					if !ok {
+						return newError("unusable as hash key: %s", pair.At(0).Type())
+					}
+					pairs = pairs.Set(key.HashKey(), object.MapPair{Key: pair.At(0), Value: pair.At(1)})
+				}
+				return pairs
+			},
+		},
+	}
+}
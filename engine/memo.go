@@ -0,0 +1,129 @@
+package engine
+
+import (
+	"container/list"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"duet/object"
+)
+
+// memoCache is a bounded LRU cache of the results of a `@memo` proc. It is safe
+// for use by the stages of a parallel pipeline.
+type memoCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List  // most recently used first
+	checked atomic.Bool // whether the body has been verified to be free of effects
+}
+
+type memoEntry struct {
+	key    string
+	result object.MemoryObject
+}
+
+// memoOf returns the result cache of a `@memo` proc, or nil for other functions.
+func memoOf(fn *object.FunctionObject) *memoCache {
+	cache, _ := fn.Memo.(*memoCache)
+	return cache
+}
+
+func newMemoCache(size int) *memoCache {
+	return &memoCache{size: size, entries: map[string]*list.Element{}, order: list.New()}
+}
+
+func (c *memoCache) get(key string) (object.MemoryObject, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*memoEntry).result, true
+}
+
+func (c *memoCache) put(key string, result object.MemoryObject) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*memoEntry).result = result
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&memoEntry{key: key, result: result})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoEntry).key)
+	}
+}
+
+// memoKey encodes arguments into a string that is equal for two argument lists
+// exactly when their values are structurally equal. Map entries are written in
+// sorted key order, so the encoding does not depend on insertion order. It
+// returns false if an argument, such as a function or a stream passed to an `fn`
+// or `any` parameter, cannot be compared by value; such calls are not cached.
+func memoKey(args []object.MemoryObject) (string, bool) {
+	var out strings.Builder
+	for _, arg := range args {
+		if !writeMemoKey(&out, arg) {
+			return "", false
+		}
+	}
+	return out.String(), true
+}
+
+func writeMemoKey(out *strings.Builder, obj object.MemoryObject) bool {
+	switch obj := obj.(type) {
+	case *object.IntegerObject:
+		out.WriteString("i")
+		out.WriteString(strconv.FormatInt(obj.Value, 10))
+		out.WriteString(";")
+	case *object.FloatObject:
+		out.WriteString("f")
+		out.WriteString(strconv.FormatUint(math.Float64bits(obj.Value), 16))
+		out.WriteString(";")
+	case *object.StringObject:
+		out.WriteString("s")
+		out.WriteString(strconv.Itoa(len(obj.Value)))
+		out.WriteString(":")
+		out.WriteString(obj.Value)
+	case *object.BooleanObject:
+		if obj.Value {
+			out.WriteString("T")
+		} else {
+			out.WriteString("F")
+		}
+	case *object.NilObject:
+		out.WriteString("n")
+	case *object.FailObject:
+		out.WriteString("x")
+		out.WriteString(strconv.Itoa(len(obj.Message)))
+		out.WriteString(":")
+		out.WriteString(obj.Message)
+	case *object.ListObject:
+		out.WriteString("[")
+		for _, el := range obj.All() {
+			if !writeMemoKey(out, el) {
+				return false
+			}
+		}
+		out.WriteString("]")
+	case *object.MapObject:
+		out.WriteString("{")
+		for _, pair := range obj.SortedPairs() {
+			if !writeMemoKey(out, pair.Key) || !writeMemoKey(out, pair.Value) {
+				return false
+			}
+		}
+		out.WriteString("}")
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,297 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"duet/ast"
+	"duet/lexer"
+	"duet/object"
+	"duet/parser"
+	"duet/token"
+)
+
+// Module is an imported file after it has been parsed, optimized and checked.
+// Every import of the same file while compiling a script shares one Module, and
+// each engine runs it once, in its own globals, the first time it is imported.
+type Module struct {
+	Path   string
+	Script *Script
+
+	functions map[string]*ast.FunctionStatement // top-level functions, exported or not
+
+	mu      sync.Mutex
+	effects *EffectChecker // answers effect questions about the module's functions
+}
+
+func newModule(path string, script *Script) *Module {
+	m := &Module{Path: path, Script: script, functions: map[string]*ast.FunctionStatement{}}
+	for _, stmt := range script.Program.Statements {
+		if fs, ok := stmt.(*ast.FunctionStatement); ok {
+			m.functions[fs.Name.Value] = fs
+		}
+	}
+	m.effects = NewEffectChecker(object.NewMemory())
+	m.effects.Check(script.Program)
+	return m
+}
+
+// isExported reports whether a name defined by a module can be used outside it.
+// Names starting with an underscore are private to the module.
+func isExported(name string) bool {
+	return !strings.HasPrefix(name, "_")
+}
+
+// function returns the exported function called name, or an error message.
+func (m *Module) function(alias, name string) (*ast.FunctionStatement, string) {
+	fs, ok := m.functions[name]
+	switch {
+	case !ok:
+		return nil, fmt.Sprintf("module %s has no function %s", alias, name)
+	case !isExported(name):
+		return nil, fmt.Sprintf("%s.%s is not exported", alias, name)
+	}
+	return fs, ""
+}
+
+// effect describes the effect reached by using the exported function name, as
+// EffectChecker.effectOf does for the functions of the program being checked.
+func (m *Module) effect(alias, name string) string {
+	fs, msg := m.function(alias, name)
+	if msg != "" {
+		return ""
+	}
+	switch fs.Token.Type {
+	case token.CONS:
+		return "cons " + alias + "." + name
+	case token.SUPP:
+		m.mu.Lock()
+		inner := m.effects.suppEffect(name, fs.Body)
+		m.mu.Unlock()
+		if inner != "" {
+			return fmt.Sprintf("supp %s.%s, which calls %s", alias, name, inner)
+		}
+	}
+	return ""
+}
+
+// imports returns the modules a program imports, by the name they are bound to.
+func imports(program *ast.Program) map[string]*Module {
+	modules := map[string]*Module{}
+	for _, stmt := range program.Statements {
+		if imp, ok := stmt.(*ast.ImportStatement); ok {
+			if module, ok := imp.Module.(*Module); ok {
+				modules[imp.Name.Value] = module
+			}
+		}
+	}
+	return modules
+}
+
+// moduleNamed returns the module bound to name by the program being checked or,
+// for modules imported by earlier REPL lines, in globals.
+func moduleNamed(name string, modules map[string]*Module, globals *object.Memory) *Module {
+	if module, ok := modules[name]; ok {
+		return module
+	}
+	if val, ok := globals.Get(name); ok {
+		if obj, ok := val.(*object.ModuleObject); ok {
+			module, _ := obj.Module.(*Module)
+			return module
+		}
+	}
+	return nil
+}
+
+// loader reads and compiles the modules imported by a script, and by the modules
+// it imports.
+type loader struct {
+	options CompileOptions
+	fsys    fs.FS
+	modules map[string]*Module // by path
+	stack   []string           // files being compiled, innermost last
+}
+
+func newLoader(options CompileOptions) *loader {
+	l := &loader{options: options, fsys: options.FS, modules: map[string]*Module{}}
+	if l.fsys == nil {
+		l.fsys = object.OSFS{}
+	}
+	if options.Path != "" {
+		l.stack = []string{filepath.Clean(options.Path)}
+	}
+	return l
+}
+
+// compile parses source, loads its imports, optimizes it and checks it. file is
+// where source was read from, or "" for a script given as a string. If dump is set,
+// it receives the program before it is checked.
+func (l *loader) compile(source, file string, globals *object.Memory, dump io.Writer) (*Script, error) {
+	p := parser.NewParser(lexer.New(source))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) > 0 {
+		return nil, l.errorIn(file, &ParseError{Errors: errs})
+	}
+
+	for _, stmt := range program.Statements {
+		imp, ok := stmt.(*ast.ImportStatement)
+		if !ok {
+			continue
+		}
+		module, err := l.load(imp.Path, file)
+		if err != nil {
+			return nil, err
+		}
+		imp.Module = module
+	}
+
+	if l.options.Optimize {
+		program = Optimize(program, globals)
+	}
+	if dump != nil {
+		io.WriteString(dump, program.String())
+	}
+	if err := check(program, globals); err != nil {
+		return nil, l.errorIn(file, err)
+	}
+	return &Script{Program: program}, nil
+}
+
+// errorIn prefixes the messages of an error found in a module with its path.
+func (l *loader) errorIn(file string, err error) error {
+	if file == l.options.Path {
+		return err
+	}
+	switch err := err.(type) {
+	case *ParseError:
+		errs := make([]string, len(err.Errors))
+		for i, msg := range err.Errors {
+			errs[i] = file + ": " + msg
+		}
+		return &ParseError{Errors: errs}
+	case *object.ErrorObject:
+		return newError("%s: %s", file, err.Message)
+	}
+	return err
+}
+
+// load returns the module imported as name by the file importer.
+func (l *loader) load(name, importer string) (*Module, error) {
+	fsys, path, err := l.find(name, importer)
+	if err != nil {
+		return nil, err
+	}
+	if i := slices.Index(l.stack, path); i >= 0 {
+		return nil, newError("import cycle: %s", strings.Join(append(slices.Clone(l.stack[i:]), path), " -> "))
+	}
+	if module, ok := l.modules[path]; ok {
+		return module, nil
+	}
+
+	source, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return nil, newError("could not read module %s: %s", path, err)
+	}
+	l.stack = append(l.stack, path)
+	script, err := l.compile(string(source), path, object.NewMemory(), nil)
+	l.stack = l.stack[:len(l.stack)-1]
+	if err != nil {
+		return nil, err
+	}
+
+	module := newModule(path, script)
+	l.modules[path] = module
+	return module, nil
+}
+
+// find resolves an import path and returns the file system it was found in.
+// Paths starting with "std/" name standard library modules; others are looked
+// up relative to the directory of the importing file first, then to each
+// directory of the module path. ".duet" may be left out.
+func (l *loader) find(name, importer string) (fs.FS, string, error) {
+	if isStd(name) {
+		if path, ok := findStd(name); ok {
+			return stdlib, path, nil
+		}
+		return nil, "", newError("no standard library module %q", name)
+	}
+	dirs := []string{""}
+	if !filepath.IsAbs(name) {
+		dirs = append([]string{filepath.Dir(importer)}, l.options.ModulePath...)
+	}
+	for _, dir := range dirs {
+		path := filepath.Join(dir, name)
+		candidates := []string{path}
+		if filepath.Ext(path) == "" {
+			candidates = append(candidates, path+".duet")
+		}
+		for _, candidate := range candidates {
+			info, err := fs.Stat(l.fsys, candidate)
+			if errors.Is(err, object.ErrDenied) {
+				return nil, "", newError("could not import %q: %s", name, err)
+			}
+			if err == nil && !info.IsDir() {
+				return l.fsys, candidate, nil
+			}
+		}
+	}
+	if importer == "" {
+		return nil, "", newError("module %q not found", name)
+	}
+	return nil, "", newError("module %q not found (imported by %s)", name, importer)
+}
+
+// importModule binds the module of stmt in mem, running it first if e has not
+// run it yet.
+func (e *ExcutionEngine) importModule(stmt *ast.ImportStatement, mem *object.Memory) object.MemoryObject {
+	module, ok := stmt.Module.(*Module)
+	if !ok {
+		return newError("module %q was not loaded", stmt.Path)
+	}
+	obj, ok := e.modules[module.Path]
+	if !ok {
+		globals := object.NewMemory()
+		var result object.MemoryObject
+		if e.Options.UseVM {
+			code, err := module.Script.compiled()
+			if err != nil {
+				return newError("%s", err)
+			}
+			result = NewVM(e, globals).Run(code)
+		} else {
+			result = e.Eval(module.Script.Program, globals)
+		}
+		if isError(result) {
+			return result
+		}
+		obj = &object.ModuleObject{Path: module.Path, Globals: globals, Module: module}
+		if e.modules == nil {
+			e.modules = map[string]*object.ModuleObject{}
+		}
+		e.modules[module.Path] = obj
+	}
+	mem.Set(stmt.Name.Value, obj)
+	return nil
+}
+
+// member returns the function a module exports as name.
+func member(module object.MemoryObject, alias, name string) object.MemoryObject {
+	obj, ok := module.(*object.ModuleObject)
+	if !ok {
+		return newError("%s is not a module, got %s", alias, module.Type())
+	}
+	if !isExported(name) {
+		return newError("%s.%s is not exported", alias, name)
+	}
+	val, ok := obj.Globals.Get(name)
+	if fn, isFunction := val.(*object.FunctionObject); ok && isFunction {
+		return fn
+	}
+	return newError("module %s has no function %s", alias, name)
+}
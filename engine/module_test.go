@@ -0,0 +1,46 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"duet/object"
+)
+
+func TestImportsObeySandbox(t *testing.T) {
+	files := object.NewMemFS(map[string]string{
+		"data/m.duet":   "proc f(x:int):int -> x + 1",
+		"secret/m.duet": "proc f(x:int):int -> x + 2",
+	})
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`import "data/m.duet" as m
+m.f(1)`, "=> 2"},
+		{`import "std/math" as math
+math.max(2, 3)`, "=> 3"},
+		{`import "secret/m.duet" as m
+m.f(1)`, `compile error: could not import "secret/m.duet": read secret/m.duet: permission denied`},
+		{`supp s:str? -> read("secret/m.duet")
+fail_code(s)`, "=> PERMISSION_DENIED"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		io := &object.IO{Stdout: &out, FS: files, Sandbox: &object.Sandbox{ReadRoots: []string{"data"}}}
+		e := New(EngineOptions{IO: io})
+		var got string
+		if script, err := CompileScript(tt.source, CompileOptions{Globals: e.Memory, FS: io}); err != nil {
+			got = "compile error: " + err.Error()
+		} else if result, err := e.Exec(context.Background(), script); err != nil {
+			got = "error: " + err.Error()
+		} else {
+			got = "=> " + result.Inspect()
+		}
+		if got != tt.want {
+			t.Errorf("%s:\ngot  %q\nwant %q", strings.SplitN(tt.source, "\n", 2)[0], got, tt.want)
+		}
+	}
+}
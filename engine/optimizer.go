@@ -0,0 +1,835 @@
+package engine
+
+import (
+	"strconv"
+
+	"duet/ast"
+	"duet/object"
+	"duet/token"
+)
+
+// Optimizer rewrites a parsed program into an equivalent one that does less work:
+//
+//   - arithmetic, comparisons and string concatenation on literals are folded;
+//   - calls to trivial procs (bodies made only of parameters, literals and
+//     operators) with literal arguments are replaced by their result;
+//   - `for x in (for ...) then ...` and `map(for ..., f)` / `for ... |> map(f)`
+//     are fused into a single for expression, so no intermediate list is built.
+//
+// Rewrites that change the order of evaluation are only applied to expressions
+// without effects, so the program's output stays the same.
+type Optimizer struct {
+	globals   *object.Memory
+	defined   map[string]bool                   // every function name defined by the program
+	functions map[string]*ast.FunctionStatement // functions defined exactly once
+	index     map[string]int                    // statement index of each function definition
+	purity    map[string]bool
+	visiting  map[string]bool
+}
+
+// Optimize rewrites program in place and returns it. Functions already set in
+// globals (e.g. by earlier REPL lines) are never inlined or assumed pure.
+func Optimize(program *ast.Program, globals *object.Memory) *ast.Program {
+	o := &Optimizer{
+		globals:   globals,
+		defined:   map[string]bool{},
+		functions: map[string]*ast.FunctionStatement{},
+		index:     map[string]int{},
+		purity:    map[string]bool{},
+		visiting:  map[string]bool{},
+	}
+	redefined := map[string]bool{}
+	for i, stmt := range program.Statements {
+		if fs, ok := stmt.(*ast.FunctionStatement); ok {
+			if _, ok := o.functions[fs.Name.Value]; ok {
+				redefined[fs.Name.Value] = true
+			}
+			o.defined[fs.Name.Value] = true
+			o.functions[fs.Name.Value] = fs
+			o.index[fs.Name.Value] = i
+		}
+	}
+	for name := range redefined {
+		delete(o.functions, name)
+	}
+
+	for i, stmt := range program.Statements {
+		switch stmt := stmt.(type) {
+		case *ast.ExpressionStatement:
+			stmt.Expression = o.optimize(stmt.Expression, &optScope{site: i})
+		case *ast.FunctionStatement:
+			scope := &optScope{site: i, locals: map[string]bool{}}
+			for _, param := range stmt.Parameters {
+				scope.locals[param.Name.Value] = true
+			}
+			stmt.Body = o.optimize(stmt.Body, scope)
+		}
+	}
+	return program
+}
+
+// optScope tracks the local names visible at a point of the program, which hide
+// functions of the same name, and the statement the code belongs to.
+type optScope struct {
+	site   int
+	locals map[string]bool
+}
+
+func (s *optScope) with(names ...*ast.Identifier) *optScope {
+	locals := map[string]bool{}
+	for name := range s.locals {
+		locals[name] = true
+	}
+	for _, name := range names {
+		if name != nil {
+			locals[name.Value] = true
+		}
+	}
+	return &optScope{site: s.site, locals: locals}
+}
+
+func (o *Optimizer) optimize(node ast.Expression, scope *optScope) ast.Expression {
+	switch node := node.(type) {
+	case *ast.PrefixExpression:
+		node.Right = o.optimize(node.Right, scope)
+		if right, ok := literalValue(node.Right); ok {
+			if folded, ok := valueLiteral(evalPrefixExpression(node.Operator, right)); ok {
+				return folded
+			}
+		}
+		return node
+
+	case *ast.YieldExpression:
+		node.Value = o.optimize(node.Value, scope)
+		return node
+
+	case *ast.SpawnExpression:
+		for i, arg := range node.Call.Arguments {
+			node.Call.Arguments[i] = o.optimize(arg, scope)
+		}
+		return node
+
+	case *ast.SelectExpression:
+		for _, c := range node.Cases {
+			for i, arg := range c.Call.Arguments {
+				c.Call.Arguments[i] = o.optimize(arg, scope)
+			}
+			c.Body = o.optimize(c.Body, scope.with(c.Variable))
+		}
+		if node.Default != nil {
+			node.Default = o.optimize(node.Default, scope)
+		}
+		return node
+
+	case *ast.InfixExpression:
+		node.Left = o.optimize(node.Left, scope)
+		node.Right = o.optimize(node.Right, scope)
+		if node.Operator == "|>" {
+			if call, ok := node.Right.(*ast.CallExpression); ok && len(call.Arguments) == 1 {
+				if fused, ok := o.fuseMap(node.Left, call.Function, call.Arguments[0], scope); ok {
+					return fused
+				}
+			}
+			return node
+		}
+		if node.IsPipeline() {
+			return node
+		}
+		return foldInfix(node)
+
+	case *ast.IfExpression:
+		node.Condition = o.optimize(node.Condition, scope)
+		node.Consequence = o.optimize(node.Consequence, scope)
+		if node.Alternative != nil {
+			node.Alternative = o.optimize(node.Alternative, scope)
+		}
+		return node
+
+	case *ast.MatchExpression:
+		node.Subject = o.optimize(node.Subject, scope)
+		for _, c := range node.Cases {
+			c.Condition = o.optimize(c.Condition, scope)
+			c.Consequence = o.optimize(c.Consequence, scope)
+		}
+		if node.Default != nil {
+			node.Default = o.optimize(node.Default, scope)
+		}
+		return node
+
+	case *ast.ForExpression:
+		inner := scope
+		for _, gen := range node.Generators {
+			gen.Collection = o.optimize(gen.Collection, inner)
+			inner = inner.with(gen.Key, gen.Variable)
+			if gen.Condition != nil {
+				gen.Condition = o.optimize(gen.Condition, inner)
+			}
+		}
+		if node.MapKey != nil {
+			node.MapKey = o.optimize(node.MapKey, inner)
+		}
+		node.Body = o.optimize(node.Body, inner)
+		if fused, ok := o.fuseFor(node, scope); ok {
+			return fused
+		}
+		return node
+
+	case *ast.CallExpression:
+		node.Function = o.optimize(node.Function, scope)
+		for i, arg := range node.Arguments {
+			node.Arguments[i] = o.optimize(arg, scope)
+		}
+		if name, ok := node.Function.(*ast.Identifier); ok && name.Value == "map" && len(node.Arguments) == 2 {
+			if fused, ok := o.fuseMap(node.Arguments[0], node.Function, node.Arguments[1], scope); ok {
+				return fused
+			}
+		}
+		if inlined, ok := o.inline(node, scope); ok {
+			return inlined
+		}
+		return node
+
+	case *ast.ListLiteral:
+		for i, el := range node.Elements {
+			node.Elements[i] = o.optimize(el, scope)
+		}
+		return node
+
+	case *ast.MapLiteral:
+		pairs := make(map[ast.Expression]ast.Expression, len(node.Pairs))
+		for key, value := range node.Pairs {
+			pairs[o.optimize(key, scope)] = o.optimize(value, scope)
+		}
+		node.Pairs = pairs
+		return node
+
+	case *ast.IndexExpression:
+		node.Left = o.optimize(node.Left, scope)
+		node.Index = o.optimize(node.Index, scope)
+		return node
+	}
+	return node
+}
+
+// foldInfix replaces an operation on two literals by its result. Operations that
+// fail at run time are left alone so the error is still reported when evaluated,
+// and string repetition is left to the engine, which checks it against MaxAllocSize.
+func foldInfix(node *ast.InfixExpression) ast.Expression {
+	left, ok := literalValue(node.Left)
+	if !ok {
+		return node
+	}
+	right, ok := literalValue(node.Right)
+	if !ok {
+		return node
+	}
+	if _, ok := left.(*object.StringObject); ok && node.Operator == "*" {
+		return node
+	}
+	if folded, ok := valueLiteral(evalInfixExpression(node.Operator, left, right)); ok {
+		return folded
+	}
+	return node
+}
+
+// inline replaces a call to a trivial proc whose arguments are all literals by the
+// literal it evaluates to. Argument and return types are checked exactly as a call would.
+func (o *Optimizer) inline(call *ast.CallExpression, scope *optScope) (ast.Expression, bool) {
+	fs, ok := o.callee(call.Function, scope)
+	if !ok || fs.Token.Type != token.PROC || !isTrivial(fs.Body, fs.Parameters) {
+		return nil, false
+	}
+
+	args := make([]object.MemoryObject, len(call.Arguments))
+	bindings := map[string]ast.Expression{}
+	for i, arg := range call.Arguments {
+		value, ok := literalValue(arg)
+		if !ok {
+			return nil, false
+		}
+		args[i] = value
+	}
+	fn := &object.FunctionObject{Name: fs.Name, Token: fs.Token, Parameters: fs.Parameters, ReturnType: fs.ReturnType}
+	if err := checkArguments(fn, args); err != nil {
+		return nil, false
+	}
+	for i, param := range fs.Parameters {
+		bindings[param.Name.Value] = call.Arguments[i]
+	}
+
+	result := o.optimize(substitute(fs.Body, bindings), &optScope{site: scope.site})
+	value, ok := literalValue(result)
+	if !ok || isError(checkReturnValue(fn, value)) {
+		return nil, false
+	}
+	return result, true
+}
+
+// callee returns the function a call site refers to, if it can be known statically:
+// a global defined exactly once, before the statement containing the call.
+func (o *Optimizer) callee(function ast.Expression, scope *optScope) (*ast.FunctionStatement, bool) {
+	name, ok := function.(*ast.Identifier)
+	if !ok || scope.locals[name.Value] {
+		return nil, false
+	}
+	fs, ok := o.functions[name.Value]
+	if !ok || o.index[name.Value] >= scope.site {
+		return nil, false
+	}
+	return fs, true
+}
+
+// isTrivial reports whether body only combines parameters and literals with operators.
+func isTrivial(body ast.Expression, params []*ast.Parameter) bool {
+	switch body := body.(type) {
+	case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.StringLiteral, *ast.BooleanLiteral, *ast.NilLiteral:
+		return true
+	case *ast.Identifier:
+		for _, param := range params {
+			if param.Name.Value == body.Value {
+				return true
+			}
+		}
+		return false
+	case *ast.PrefixExpression:
+		return isTrivial(body.Right, params)
+	case *ast.InfixExpression:
+		return !body.IsPipeline() && isTrivial(body.Left, params) && isTrivial(body.Right, params)
+	}
+	return false
+}
+
+// fuseFor merges a for expression whose first generator iterates over another
+// (list-building) for expression:
+//
+//	for x in (for y in ys if p then f(y)) if q then g(x)  =>  for y in ys if (if p then q' else false) then g'
+//
+// where q' and g' have f(y) substituted for x. Both loops must be free of effects.
+func (o *Optimizer) fuseFor(outer *ast.ForExpression, scope *optScope) (ast.Expression, bool) {
+	first := outer.Generators[0]
+	inner, ok := first.Collection.(*ast.ForExpression)
+	if !ok || inner.MapKey != nil || first.Key != nil {
+		return nil, false
+	}
+	if !o.isPure(inner, scope) || !o.isPure(outer, scope) {
+		return nil, false
+	}
+
+	// The rest of the outer loop, which will see the inner body instead of x.
+	var parts []ast.Expression
+	if first.Condition != nil {
+		parts = append(parts, first.Condition)
+	}
+	for _, gen := range outer.Generators[1:] {
+		if gen.Key != nil && gen.Key.Value == first.Variable.Value || gen.Variable.Value == first.Variable.Value {
+			return nil, false
+		}
+		parts = append(parts, gen.Collection)
+		if gen.Condition != nil {
+			parts = append(parts, gen.Condition)
+		}
+	}
+	if outer.MapKey != nil {
+		parts = append(parts, outer.MapKey)
+	}
+	parts = append(parts, outer.Body)
+
+	// Moving the inner body must not change what its names, or the names of the
+	// outer loop, refer to.
+	innerNames := map[string]bool{}
+	for _, gen := range inner.Generators {
+		if gen.Key != nil {
+			innerNames[gen.Key.Value] = true
+		}
+		innerNames[gen.Variable.Value] = true
+	}
+	uses := 0
+	for _, part := range parts {
+		for _, ident := range identifiers(part) {
+			if innerNames[ident] {
+				return nil, false
+			}
+			if ident == first.Variable.Value {
+				uses++
+			}
+		}
+	}
+	// Nor may a generator of the outer loop, or a loop or select case nested in
+	// it, bind a name the inner body uses where the body is substituted.
+	innerFree := identifiers(inner.Body)
+	for _, gen := range outer.Generators[1:] {
+		for _, ident := range innerFree {
+			if gen.Key != nil && gen.Key.Value == ident || gen.Variable.Value == ident {
+				return nil, false
+			}
+		}
+	}
+	for _, part := range parts {
+		shadowing := binders(part)
+		for _, ident := range innerFree {
+			if shadowing[ident] {
+				return nil, false
+			}
+		}
+	}
+	if uses != 1 && !isAtom(inner.Body) {
+		return nil, false
+	}
+
+	bindings := map[string]ast.Expression{first.Variable.Value: inner.Body}
+	generators := append([]*ast.ForGenerator{}, inner.Generators...)
+	last := *generators[len(generators)-1]
+	if first.Condition != nil {
+		condition := substitute(first.Condition, bindings)
+		if last.Condition != nil {
+			condition = &ast.IfExpression{
+				Token:       token.Token{Type: token.IF, Literal: "if"},
+				Condition:   last.Condition,
+				Consequence: condition,
+				Alternative: &ast.BooleanLiteral{Token: token.Token{Type: token.FALSE, Literal: "false"}, Value: false},
+			}
+		}
+		last.Condition = condition
+	}
+	generators[len(generators)-1] = &last
+	for _, gen := range outer.Generators[1:] {
+		generators = append(generators, &ast.ForGenerator{
+			Key:        gen.Key,
+			Variable:   gen.Variable,
+			Collection: substitute(gen.Collection, bindings),
+			Condition:  substituteOptional(gen.Condition, bindings),
+		})
+	}
+
+	return &ast.ForExpression{
+		Token:      outer.Token,
+		Generators: generators,
+		MapKey:     substituteOptional(outer.MapKey, bindings),
+		Body:       substitute(outer.Body, bindings),
+	}, true
+}
+
+// fuseMap turns `map(for ... then e, f)` (or `for ... then e |> map(f)`) into
+// `for ... then f(e)` when f is a known function without effects.
+func (o *Optimizer) fuseMap(list, mapFn, fn ast.Expression, scope *optScope) (ast.Expression, bool) {
+	name, ok := mapFn.(*ast.Identifier)
+	if !ok || name.Value != "map" || scope.locals["map"] || !o.isBuiltin("map") {
+		return nil, false
+	}
+	fe, ok := list.(*ast.ForExpression)
+	if !ok || fe.MapKey != nil {
+		return nil, false
+	}
+	ident, ok := fn.(*ast.Identifier)
+	if !ok || scope.locals[ident.Value] || !o.isPure(fe, scope) || !o.isPure(ident, scope) {
+		return nil, false
+	}
+	// The call moves inside the loop, where a generator variable could hide f.
+	for _, gen := range fe.Generators {
+		if gen.Key != nil && gen.Key.Value == ident.Value || gen.Variable.Value == ident.Value {
+			return nil, false
+		}
+	}
+	if _, ok := o.functions[ident.Value]; !ok && !o.isBuiltin(ident.Value) {
+		return nil, false
+	}
+
+	return &ast.ForExpression{
+		Token:      fe.Token,
+		Generators: fe.Generators,
+		Body: &ast.CallExpression{
+			Token:     token.Token{Type: token.LPAREN, Literal: "("},
+			Function:  ident,
+			Arguments: []ast.Expression{fe.Body},
+		},
+	}, true
+}
+
+// isPure reports whether evaluating node has no effects: it only calls builtins
+// that are not Effectful and functions of the program that are themselves pure.
+// Calling a local variable, or handing one to a higher-order builtin, may run any
+// function (e.g. one taken from a list), so it counts as an effect.
+func (o *Optimizer) isPure(node ast.Expression, scope *optScope) bool {
+	if isGenerator(node) {
+		return false
+	}
+	pure := true
+	walkExpression(node, func(ident *ast.Identifier, bound, called bool) {
+		local := bound || scope.locals[ident.Value]
+		switch {
+		case local && called:
+			pure = false
+		case !local && !o.isPureName(ident.Value):
+			pure = false
+		}
+	})
+	return pure
+}
+
+// isPureName reports whether a global name refers to a pure function or builtin.
+// Names that are not (yet) known, such as functions defined on an earlier REPL
+// line, are treated as effectful.
+func (o *Optimizer) isPureName(name string) bool {
+	fs, ok := o.functions[name]
+	if !ok {
+		return o.isBuiltin(name) && !builtins[name].Effectful
+	}
+	if pure, ok := o.purity[name]; ok {
+		return pure
+	}
+	if o.visiting[name] {
+		return true // recursion does not add effects of its own
+	}
+	o.visiting[name] = true
+	scope := &optScope{locals: map[string]bool{}}
+	for _, param := range fs.Parameters {
+		scope.locals[param.Name.Value] = true
+	}
+	pure := o.isPure(fs.Body, scope)
+	delete(o.visiting, name)
+	o.purity[name] = pure
+	return pure
+}
+
+// isBuiltin reports whether a global name refers to a builtin rather than a function.
+func (o *Optimizer) isBuiltin(name string) bool {
+	if o.defined[name] {
+		return false
+	}
+	if _, ok := o.globals.Get(name); ok {
+		return false
+	}
+	_, ok := builtins[name]
+	return ok
+}
+
+// walkExpression calls visit for every identifier in node. bound tells whether a
+// for generator inside node binds the name, and called whether the identifier is
+// called, directly or as the argument of a higher-order builtin.
+func walkExpression(node ast.Expression, visit func(ident *ast.Identifier, bound, called bool)) {
+	walkBound(node, map[string]bool{}, false, visit)
+}
+
+func walkBound(node ast.Expression, bound map[string]bool, called bool, visit func(*ast.Identifier, bool, bool)) {
+	walk := func(node ast.Expression) { walkBound(node, bound, false, visit) }
+	switch node := node.(type) {
+	case *ast.Identifier:
+		visit(node, bound[node.Value], called)
+	case *ast.MemberExpression:
+		// The functions of other modules are not analyzed, so using one counts
+		// as using the module name, which is never pure.
+		visit(node.Module, bound[node.Module.Value], called)
+	case *ast.PrefixExpression:
+		walk(node.Right)
+	case *ast.YieldExpression:
+		walk(node.Value)
+	case *ast.SpawnExpression:
+		walkBound(node.Call, bound, false, visit)
+	case *ast.SelectExpression:
+		for _, c := range node.Cases {
+			walkBound(c.Call, bound, false, visit)
+			if c.Variable == nil {
+				walk(c.Body)
+				continue
+			}
+			inner := map[string]bool{c.Variable.Value: true}
+			for name := range bound {
+				inner[name] = true
+			}
+			walkBound(c.Body, inner, false, visit)
+		}
+		if node.Default != nil {
+			walk(node.Default)
+		}
+	case *ast.InfixExpression:
+		if node.IsPipeline() {
+			// The right side of a pipeline is called with the left side.
+			walk(node.Left)
+			walkBound(node.Right, bound, true, visit)
+			return
+		}
+		walk(node.Left)
+		walk(node.Right)
+	case *ast.IfExpression:
+		walk(node.Condition)
+		walk(node.Consequence)
+		if node.Alternative != nil {
+			walk(node.Alternative)
+		}
+	case *ast.MatchExpression:
+		walk(node.Subject)
+		for _, c := range node.Cases {
+			walk(c.Condition)
+			walk(c.Consequence)
+		}
+		if node.Default != nil {
+			walk(node.Default)
+		}
+	case *ast.ForExpression:
+		inner := map[string]bool{}
+		for name := range bound {
+			inner[name] = true
+		}
+		for _, gen := range node.Generators {
+			walkBound(gen.Collection, inner, false, visit)
+			if gen.Key != nil {
+				inner[gen.Key.Value] = true
+			}
+			inner[gen.Variable.Value] = true
+			if gen.Condition != nil {
+				walkBound(gen.Condition, inner, false, visit)
+			}
+		}
+		if node.MapKey != nil {
+			walkBound(node.MapKey, inner, false, visit)
+		}
+		walkBound(node.Body, inner, false, visit)
+	case *ast.CallExpression:
+		walkBound(node.Function, bound, true, visit)
+		higherOrder := false
+		if name, ok := node.Function.(*ast.Identifier); ok && !bound[name.Value] {
+			if builtin, ok := builtins[name.Value]; ok && builtin.HigherOrder != nil {
+				higherOrder = true
+			}
+		}
+		for _, arg := range node.Arguments {
+			walkBound(arg, bound, higherOrder, visit)
+		}
+	case *ast.ListLiteral:
+		for _, el := range node.Elements {
+			walk(el)
+		}
+	case *ast.MapLiteral:
+		for key, value := range node.Pairs {
+			walk(key)
+			walk(value)
+		}
+	case *ast.IndexExpression:
+		walk(node.Left)
+		walk(node.Index)
+	}
+}
+
+// identifiers returns the free names used in node.
+func identifiers(node ast.Expression) []string {
+	var names []string
+	walkExpression(node, func(ident *ast.Identifier, bound, _ bool) {
+		if !bound {
+			names = append(names, ident.Value)
+		}
+	})
+	return names
+}
+
+// binders returns the names bound by the for generators and select cases under node.
+func binders(node ast.Expression) map[string]bool {
+	names := map[string]bool{}
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.ForExpression:
+			for _, gen := range n.Generators {
+				if gen.Key != nil {
+					names[gen.Key.Value] = true
+				}
+				names[gen.Variable.Value] = true
+			}
+		case *ast.SelectExpression:
+			for _, c := range n.Cases {
+				if c.Variable != nil {
+					names[c.Variable.Value] = true
+				}
+			}
+		}
+		return true
+	})
+	return names
+}
+
+// isAtom reports whether evaluating node twice costs no more than reading a variable.
+func isAtom(node ast.Expression) bool {
+	switch node.(type) {
+	case *ast.Identifier, *ast.IntegerLiteral, *ast.FloatLiteral, *ast.StringLiteral, *ast.BooleanLiteral, *ast.NilLiteral:
+		return true
+	}
+	return false
+}
+
+func substituteOptional(node ast.Expression, bindings map[string]ast.Expression) ast.Expression {
+	if node == nil {
+		return nil
+	}
+	return substitute(node, bindings)
+}
+
+// substitute returns a copy of node with free identifiers replaced by copies of
+// their bindings. Every node is copied, because the resolver annotates identifiers
+// in place and the same node must not appear in two scopes.
+func substitute(node ast.Expression, bindings map[string]ast.Expression) ast.Expression {
+	switch node := node.(type) {
+	case *ast.Identifier:
+		if value, ok := bindings[node.Value]; ok {
+			return substitute(value, nil)
+		}
+		copied := *node
+		return &copied
+	case *ast.IntegerLiteral:
+		copied := *node
+		return &copied
+	case *ast.FloatLiteral:
+		copied := *node
+		return &copied
+	case *ast.StringLiteral:
+		copied := *node
+		return &copied
+	case *ast.BooleanLiteral:
+		copied := *node
+		return &copied
+	case *ast.NilLiteral:
+		copied := *node
+		return &copied
+	case *ast.FailExpression:
+		copied := *node
+		return &copied
+	case *ast.MemberExpression:
+		return &ast.MemberExpression{Token: node.Token, Module: copyIdentifier(node.Module), Member: copyIdentifier(node.Member)}
+	case *ast.YieldExpression:
+		return &ast.YieldExpression{Token: node.Token, Value: substitute(node.Value, bindings)}
+	case *ast.SpawnExpression:
+		return &ast.SpawnExpression{Token: node.Token, Call: substitute(node.Call, bindings).(*ast.CallExpression)}
+	case *ast.SelectExpression:
+		cases := make([]*ast.SelectCase, len(node.Cases))
+		for i, c := range node.Cases {
+			inner := bindings
+			if c.Variable != nil {
+				inner = unbind(bindings, c.Variable)
+			}
+			cases[i] = &ast.SelectCase{
+				Call:     substitute(c.Call, bindings).(*ast.CallExpression),
+				Variable: copyIdentifier(c.Variable),
+				Body:     substitute(c.Body, inner),
+			}
+		}
+		return &ast.SelectExpression{Token: node.Token, Cases: cases, Default: substituteOptional(node.Default, bindings)}
+	case *ast.PrefixExpression:
+		return &ast.PrefixExpression{Token: node.Token, Operator: node.Operator, Right: substitute(node.Right, bindings)}
+	case *ast.InfixExpression:
+		return &ast.InfixExpression{Token: node.Token, Operator: node.Operator, Left: substitute(node.Left, bindings), Right: substitute(node.Right, bindings)}
+	case *ast.IfExpression:
+		return &ast.IfExpression{
+			Token:       node.Token,
+			Condition:   substitute(node.Condition, bindings),
+			Consequence: substitute(node.Consequence, bindings),
+			Alternative: substituteOptional(node.Alternative, bindings),
+		}
+	case *ast.MatchExpression:
+		cases := make([]*ast.MatchCase, len(node.Cases))
+		for i, c := range node.Cases {
+			cases[i] = &ast.MatchCase{Condition: substitute(c.Condition, bindings), Consequence: substitute(c.Consequence, bindings)}
+		}
+		return &ast.MatchExpression{
+			Token:   node.Token,
+			Subject: substitute(node.Subject, bindings),
+			Cases:   cases,
+			Default: substituteOptional(node.Default, bindings),
+		}
+	case *ast.ForExpression:
+		// Generator variables hide bindings of the same name from there on.
+		inner := bindings
+		generators := make([]*ast.ForGenerator, len(node.Generators))
+		for i, gen := range node.Generators {
+			collection := substitute(gen.Collection, inner)
+			inner = unbind(inner, gen.Key, gen.Variable)
+			generators[i] = &ast.ForGenerator{
+				Key:        copyIdentifier(gen.Key),
+				Variable:   copyIdentifier(gen.Variable),
+				Collection: collection,
+				Condition:  substituteOptional(gen.Condition, inner),
+			}
+		}
+		return &ast.ForExpression{
+			Token:      node.Token,
+			Generators: generators,
+			MapKey:     substituteOptional(node.MapKey, inner),
+			Body:       substitute(node.Body, inner),
+		}
+	case *ast.CallExpression:
+		args := make([]ast.Expression, len(node.Arguments))
+		for i, arg := range node.Arguments {
+			args[i] = substitute(arg, bindings)
+		}
+		return &ast.CallExpression{Token: node.Token, Function: substitute(node.Function, bindings), Arguments: args}
+	case *ast.ListLiteral:
+		elements := make([]ast.Expression, len(node.Elements))
+		for i, el := range node.Elements {
+			elements[i] = substitute(el, bindings)
+		}
+		return &ast.ListLiteral{Token: node.Token, Elements: elements}
+	case *ast.MapLiteral:
+		pairs := make(map[ast.Expression]ast.Expression, len(node.Pairs))
+		for key, value := range node.Pairs {
+			pairs[substitute(key, bindings)] = substitute(value, bindings)
+		}
+		return &ast.MapLiteral{Token: node.Token, Pairs: pairs}
+	case *ast.IndexExpression:
+		return &ast.IndexExpression{Token: node.Token, Left: substitute(node.Left, bindings), Index: substitute(node.Index, bindings)}
+	}
+	return node
+}
+
+func unbind(bindings map[string]ast.Expression, names ...*ast.Identifier) map[string]ast.Expression {
+	result := map[string]ast.Expression{}
+	for name, value := range bindings {
+		result[name] = value
+	}
+	for _, name := range names {
+		if name != nil {
+			delete(result, name.Value)
+		}
+	}
+	return result
+}
+
+func copyIdentifier(ident *ast.Identifier) *ast.Identifier {
+	if ident == nil {
+		return nil
+	}
+	copied := *ident
+	return &copied
+}
+
+// literalValue returns the value of a literal expression.
+func literalValue(node ast.Expression) (object.MemoryObject, bool) {
+	switch node := node.(type) {
+	case *ast.IntegerLiteral:
+		return &object.IntegerObject{Value: node.Value}, true
+	case *ast.FloatLiteral:
+		return &object.FloatObject{Value: node.Value}, true
+	case *ast.StringLiteral:
+		return &object.StringObject{Value: node.Value}, true
+	case *ast.BooleanLiteral:
+		return nativeBoolToBooleanObject(node.Value), true
+	case *ast.NilLiteral:
+		return object.Nil, true
+	}
+	return nil, false
+}
+
+// valueLiteral returns a literal expression evaluating to obj, if there is one.
+func valueLiteral(obj object.MemoryObject) (ast.Expression, bool) {
+	switch obj := obj.(type) {
+	case *object.IntegerObject:
+		return &ast.IntegerLiteral{Token: token.Token{Type: token.INT, Literal: strconv.FormatInt(obj.Value, 10)}, Value: obj.Value}, true
+	case *object.FloatObject:
+		return &ast.FloatLiteral{Token: token.Token{Type: token.FLOAT, Literal: strconv.FormatFloat(obj.Value, 'g', -1, 64)}, Value: obj.Value}, true
+	case *object.StringObject:
+		return &ast.StringLiteral{Token: token.Token{Type: token.STRING, Literal: obj.Value}, Value: obj.Value}, true
+	case *object.BooleanObject:
+		if obj.Value {
+			return &ast.BooleanLiteral{Token: token.Token{Type: token.TRUE, Literal: "true"}, Value: true}, true
+		}
+		return &ast.BooleanLiteral{Token: token.Token{Type: token.FALSE, Literal: "false"}, Value: false}, true
+	case *object.NilObject:
+		return &ast.NilLiteral{Token: token.Token{Type: token.NIL, Literal: "nil"}}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"duet/lexer"
+	"duet/object"
+	"duet/parser"
+)
+
+func optimizeSource(t *testing.T, source string) string {
+	t.Helper()
+	p := parser.NewParser(lexer.New(source))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	return strings.TrimSpace(Optimize(program, object.NewMemory()).String())
+}
+
+func TestFuseFor(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			"fused",
+			`proc f(xs:list):list -> for y in (for x in xs if x > 1 then x * 10) then y + 1`,
+			"proc f(xs: list): list -> for x in xs if (x > 1) then ((x * 10) + 1)",
+		},
+		{
+			"nested loop would capture the inner body's names",
+			`proc f(xs:list):list -> for y in (for x in xs then x) then for x in [10] then x + y`,
+			"proc f(xs: list): list -> for y in for x in xs then x then for x in [10] then (x + y)",
+		},
+		{
+			"nested loop in a condition would capture the inner body's names",
+			`proc f(xs:list):list -> for y in (for x in xs then x) if len(for x in [5] if x == y then x) > 0 then y`,
+			"proc f(xs: list): list -> for y in for x in xs then x if (len(for x in [5] if (x == y) then x) > 0) then y",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := optimizeSource(t, tt.source); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
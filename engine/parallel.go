@@ -0,0 +1,355 @@
+package engine
+
+import (
+	"context"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"duet/ast"
+	"duet/object"
+	"duet/token"
+)
+
+// PARALLEL_PIPE_BUFFER is the capacity of the channel between two stages of `||>`.
+// A stage that gets this many elements ahead of the next one waits for it to catch up.
+const PARALLEL_PIPE_BUFFER = 16
+
+// pipeStage is one stage of a parallel pipeline: a function and the arguments that
+// follow the element it receives.
+type pipeStage struct {
+	fn   object.MemoryObject
+	args []object.MemoryObject
+}
+
+// parallelStages splits a chain of `||>` into its source expression and its stages.
+func parallelStages(node *ast.InfixExpression) (ast.Expression, []ast.Expression) {
+	var stages []ast.Expression
+	var source ast.Expression = node
+	for {
+		pipe, ok := source.(*ast.InfixExpression)
+		if !ok || pipe.Operator != "||>" {
+			break
+		}
+		stages = append(stages, pipe.Right)
+		source = pipe.Left
+	}
+	slices.Reverse(stages)
+	return source, stages
+}
+
+func (e *ExcutionEngine) evalParallelPipeline(node *ast.InfixExpression, mem *object.Memory) object.MemoryObject {
+	sourceExpr, stageExprs := parallelStages(node)
+	source := e.Eval(sourceExpr, mem)
+	if isError(source) {
+		return source
+	}
+	source = e.pipeSource(source)
+	if isError(source) {
+		return source
+	}
+
+	stages := make([]pipeStage, len(stageExprs))
+	for i, expr := range stageExprs {
+		if call, ok := expr.(*ast.CallExpression); ok {
+			fn := e.Eval(call.Function, mem)
+			if isError(fn) {
+				return fn
+			}
+			args := e.evalExpressions(call.Arguments, mem)
+			if len(args) > 0 && isError(args[0]) {
+				return args[0]
+			}
+			stages[i] = pipeStage{fn: fn, args: args}
+			continue
+		}
+		fn := e.Eval(expr, mem)
+		if isError(fn) {
+			return fn
+		}
+		stages[i] = pipeStage{fn: fn}
+	}
+	return e.runParallel(source, stages)
+}
+
+// fork returns an engine that runs functions on another goroutine. It shares the
+// program, memory, options and scheduler of e but has its own call stack. Its
+// steps are added to the scheduler's counter, so the step budget is shared.
+func (e *ExcutionEngine) fork(ctx context.Context) *ExcutionEngine {
+	return &ExcutionEngine{
+		Program:   e.Program,
+		Memory:    e.Memory,
+		Options:   e.Options,
+		callStack: slices.Clone(e.callStack),
+		procs:     slices.Clone(e.procs),
+		ctx:       ctx,
+		sched:     e.scheduler(),
+	}
+}
+
+// forkN forks n engines whose execution stops when the returned cancel is called
+// or when e's own context ends.
+func (e *ExcutionEngine) forkN(n int) ([]*ExcutionEngine, context.Context, context.CancelFunc) {
+	parent := e.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	forks := make([]*ExcutionEngine, n)
+	for i := range forks {
+		forks[i] = e.fork(ctx)
+	}
+	return forks, ctx, cancel
+}
+
+// join adds the steps forks have not flushed yet once they have finished.
+func (e *ExcutionEngine) join(forks []*ExcutionEngine) {
+	for _, f := range forks {
+		f.flushSteps()
+	}
+}
+
+// caller returns a Caller that runs functions on e with the backend e is configured for.
+func (e *ExcutionEngine) caller() object.Caller {
+	if e.Options.UseVM {
+		return NewVM(e, e.Memory).callFunction
+	}
+	return e.callFunction
+}
+
+// spawner returns the Spawner handed to parallel builtins. The forks it creates are
+// recorded in *forks so their steps can be joined when the builtin returns.
+func (e *ExcutionEngine) spawner(forks *[]*ExcutionEngine) object.Spawner {
+	return func(n int) ([]object.Caller, func()) {
+		spawned, _, cancel := e.forkN(n)
+		*forks = append(*forks, spawned...)
+		callers := make([]object.Caller, n)
+		for i, f := range spawned {
+			callers[i] = f.caller()
+		}
+		return callers, cancel
+	}
+}
+
+// runParallel runs the elements of source through stages, each stage on its own
+// goroutine, connected by channels of PARALLEL_PIPE_BUFFER elements. Each stage sees
+// the elements in order, so the results keep the order of the input. A FAIL element
+// skips the stages whose first parameter is not fallible and arrives in the result as
+// it is; the first ERROR stops every stage and becomes the result of the pipeline.
+//
+// A list or a stream source contributes its elements and the result is a list; any
+// other value is a single element and the result is its final value. When the last
+// stage is a cons the result is nil.
+func (e *ExcutionEngine) runParallel(source object.MemoryObject, stages []pipeStage) object.MemoryObject {
+	// Fork before the source starts: a generator source swaps e's call stack while it runs.
+	forks, ctx, cancel := e.forkN(len(stages))
+	defer cancel()
+	// Stages count as running goroutines for deadlock detection when they use channels.
+	sched := e.scheduler()
+
+	var (
+		failOnce sync.Once
+		failure  object.MemoryObject
+		wg       sync.WaitGroup
+	)
+	fail := func(err object.MemoryObject) {
+		failOnce.Do(func() {
+			failure = err
+			cancel()
+		})
+	}
+	send := func(ch chan<- object.MemoryObject, obj object.MemoryObject) bool {
+		select {
+		case ch <- obj:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	first := make(chan object.MemoryObject, PARALLEL_PIPE_BUFFER)
+	sched.enter()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer sched.leave()
+		defer close(first)
+		switch source := source.(type) {
+		case *object.ListObject:
+			for i := 0; i < source.Len(); i++ {
+				if !send(first, source.At(i)) {
+					return
+				}
+			}
+		case *object.StreamObject:
+			for el := range source.All() {
+				if isError(el) {
+					fail(el)
+					return
+				}
+				if !send(first, el) {
+					return
+				}
+			}
+		default:
+			send(first, source)
+		}
+	}()
+
+	in := first
+	for i, stage := range stages {
+		out := make(chan object.MemoryObject, PARALLEL_PIPE_BUFFER)
+		call := forks[i].caller()
+		sched.enter()
+		wg.Add(1)
+		go func(in <-chan object.MemoryObject) {
+			defer wg.Done()
+			defer sched.leave()
+			defer close(out)
+			for el := range in {
+				result := el
+				if el.Type() != object.FAIL_OBJ || acceptsFail(stage.fn) {
+					result = call(stage.fn, append([]object.MemoryObject{el}, stage.args...)...)
+				}
+				if isError(result) {
+					fail(result)
+					return
+				}
+				if !send(out, result) {
+					return
+				}
+			}
+		}(in)
+		in = out
+	}
+
+	var results []object.MemoryObject
+	for el := range in {
+		results = append(results, el)
+	}
+	wg.Wait()
+
+	e.join(forks)
+	if failure != nil {
+		return failure
+	}
+	// Stages stop sending when the context ends, leaving results short.
+	if err := contextError(ctx); err != nil {
+		return err
+	}
+	if fn, ok := stages[len(stages)-1].fn.(*object.FunctionObject); ok && fn.Token.Type == token.CONS {
+		return object.Nil
+	}
+	switch source.(type) {
+	case *object.ListObject, *object.StreamObject:
+		return e.checkSize(object.NewList(results))
+	}
+	if len(results) == 0 {
+		return newError("parallel pipeline produced no result")
+	}
+	return results[0]
+}
+
+// acceptsFail reports whether fn takes a FAIL as the element it receives in a pipeline.
+func acceptsFail(fn object.MemoryObject) bool {
+	function, ok := fn.(*object.FunctionObject)
+	return ok && len(function.Parameters) > 0 && strings.HasSuffix(function.Parameters[0].Type.Value, "?")
+}
+
+func newParallelBuiltins() map[string]*object.BuiltinObject {
+	return map[string]*object.BuiltinObject{
+		"pmap": {
+			Parallel: func(spawn object.Spawner, args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 2 && len(args) != 3 {
+					return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+				}
+				list, ok := args[0].(*object.ListObject)
+				if !ok {
+					return newError("first argument to `pmap` must be LIST, got %s", args[0].Type())
+				}
+				fn := args[1]
+				if err := checkParallelFunction("pmap", fn); err != nil {
+					return err
+				}
+				workers := runtime.NumCPU()
+				if len(args) == 3 {
+					n, ok := args[2].(*object.IntegerObject)
+					if !ok || n.Value <= 0 {
+						return newError("third argument to `pmap` must be a positive INTEGER, got %s", args[2].Inspect())
+					}
+					workers = int(n.Value)
+				}
+				return parallelMap(spawn, list, fn, min(workers, max(list.Len(), 1)))
+			},
+		},
+	}
+}
+
+// parallelMap calls fn on every element of list using workers goroutines and
+// returns the results in the order of list. The first ERROR stops the other
+// workers and is returned.
+func parallelMap(spawn object.Spawner, list *object.ListObject, fn object.MemoryObject, workers int) object.MemoryObject {
+	callers, stop := spawn(workers)
+	defer stop()
+
+	var (
+		next     atomic.Int64
+		failed   atomic.Bool
+		failOnce sync.Once
+		failure  object.MemoryObject
+		wg       sync.WaitGroup
+	)
+	results := make([]object.MemoryObject, list.Len())
+	for _, call := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !failed.Load() {
+				i := int(next.Add(1) - 1)
+				if i >= len(results) {
+					return
+				}
+				result := call(fn, list.At(i))
+				if isError(result) {
+					failOnce.Do(func() {
+						failure = result
+						failed.Store(true)
+						stop()
+					})
+					return
+				}
+				results[i] = result
+			}
+		}()
+	}
+	wg.Wait()
+
+	if failure != nil {
+		return failure
+	}
+	return object.NewList(results)
+}
+
+// checkParallelFunction makes sure fn can safely run on several goroutines at once:
+// it must be a proc, or a builtin without effects, and nothing it reaches may have
+// effects, since their order would be unpredictable.
+func checkParallelFunction(name string, fn object.MemoryObject) *object.ErrorObject {
+	switch fn := fn.(type) {
+	case *object.FunctionObject:
+		if fn.Token.Type != token.PROC {
+			return newError("`%s` needs an effect-free proc, got %s %s", name, fn.Token.Literal, fn.Name.Value)
+		}
+		if effect := findEffect(fn, map[*object.FunctionObject]bool{}); effect != "" {
+			return newError("`%s` needs an effect-free proc: proc %s calls %s", name, fn.Name.Value, effect)
+		}
+	case *object.BuiltinObject:
+		if fn.Effectful {
+			return newError("`%s` needs an effect-free proc, got an IO builtin", name)
+		}
+	default:
+		return newError("second argument to `%s` must be a function, got %s", name, fn.Type())
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package engine
+
+import (
+	"fmt"
+
+	"duet/ast"
+	"duet/object"
+	"duet/token"
+)
+
+// Resolver assigns lexical addresses to the identifiers of a program and reports
+// names that are not defined anywhere. Function parameters and for generator
+// variables become (depth, slot) addresses into the slots of a local Memory;
+// everything else is a global function or a builtin.
+type Resolver struct {
+	globals *object.Memory
+	defined map[string]bool    // functions defined by the program being resolved
+	modules map[string]*Module // modules imported by the program being resolved
+	scopes  [][]string         // innermost scope last
+	inSupp  bool               // whether the code being resolved is the body of a supp
+	errors  []string
+}
+
+// NewResolver creates a Resolver. Names already set in globals (e.g. functions
+// defined on earlier REPL lines) count as defined.
+func NewResolver(globals *object.Memory) *Resolver {
+	return &Resolver{globals: globals}
+}
+
+func (r *Resolver) Errors() []string {
+	return r.errors
+}
+
+// Resolve annotates program in place. It can be called again on the same program.
+func (r *Resolver) Resolve(program *ast.Program) {
+	r.defined = map[string]bool{}
+	r.modules = imports(program)
+	for _, stmt := range program.Statements {
+		if fs, ok := stmt.(*ast.FunctionStatement); ok {
+			r.defined[fs.Name.Value] = true
+		}
+	}
+	for name := range r.modules {
+		if r.defined[name] {
+			r.errors = append(r.errors, fmt.Sprintf("%s is both an imported module and a function", name))
+		}
+		r.defined[name] = true
+	}
+
+	for _, stmt := range program.Statements {
+		switch stmt := stmt.(type) {
+		case *ast.ExpressionStatement:
+			r.resolve(stmt.Expression)
+		case *ast.FunctionStatement:
+			params := make([]string, len(stmt.Parameters))
+			for i, param := range stmt.Parameters {
+				params[i] = param.Name.Value
+			}
+			r.scopes = [][]string{params}
+			r.inSupp = stmt.Token.Type == token.SUPP
+			r.resolve(stmt.Body)
+			r.scopes, r.inSupp = nil, false
+
+			if stmt.Token.Type == token.SUPP && isGenerator(stmt.Body) && stmt.ReturnType != nil && stmt.ReturnType.Value != "stream" {
+				r.errors = append(r.errors, fmt.Sprintf("supp %s yields values, so its return type must be stream, got %s", stmt.Name.Value, stmt.ReturnType.Value))
+			}
+		}
+	}
+}
+
+func (r *Resolver) resolve(node ast.Expression) {
+	switch node := node.(type) {
+	case *ast.Identifier:
+		r.resolveIdentifier(node)
+	case *ast.MemberExpression:
+		r.resolveMember(node)
+	case *ast.PrefixExpression:
+		r.resolve(node.Right)
+	case *ast.YieldExpression:
+		if !r.inSupp {
+			r.errors = append(r.errors, "yield can only be used in the body of a supp")
+		}
+		r.resolve(node.Value)
+	case *ast.SpawnExpression:
+		r.resolve(node.Call)
+	case *ast.SelectExpression:
+		// A case that names the received value opens a scope for its body, like
+		// the memory evalSelectExpression creates.
+		for _, c := range node.Cases {
+			r.resolve(c.Call)
+			if c.Variable == nil {
+				r.resolve(c.Body)
+				continue
+			}
+			r.scopes = append(r.scopes, []string{c.Variable.Value})
+			r.resolve(c.Body)
+			r.scopes = r.scopes[:len(r.scopes)-1]
+		}
+		if node.Default != nil {
+			r.resolve(node.Default)
+		}
+	case *ast.InfixExpression:
+		r.resolve(node.Left)
+		r.resolve(node.Right)
+	case *ast.IfExpression:
+		r.resolve(node.Condition)
+		r.resolve(node.Consequence)
+		if node.Alternative != nil {
+			r.resolve(node.Alternative)
+		}
+	case *ast.MatchExpression:
+		r.resolve(node.Subject)
+		for _, c := range node.Cases {
+			r.resolve(c.Condition)
+			r.resolve(c.Consequence)
+		}
+		if node.Default != nil {
+			r.resolve(node.Default)
+		}
+	case *ast.ForExpression:
+		r.resolveFor(node, node.Generators)
+	case *ast.CallExpression:
+		r.resolve(node.Function)
+		for _, arg := range node.Arguments {
+			r.resolve(arg)
+		}
+	case *ast.ListLiteral:
+		for _, el := range node.Elements {
+			r.resolve(el)
+		}
+	case *ast.MapLiteral:
+		for key, value := range node.Pairs {
+			r.resolve(key)
+			r.resolve(value)
+		}
+	case *ast.IndexExpression:
+		r.resolve(node.Left)
+		r.resolve(node.Index)
+	}
+}
+
+// resolveFor opens one scope per generator, mirroring the memory evalForGenerators
+// creates for each iteration: the key variable (if any) takes slot 0, then the value.
+func (r *Resolver) resolveFor(fe *ast.ForExpression, generators []*ast.ForGenerator) {
+	if len(generators) == 0 {
+		if fe.MapKey != nil {
+			r.resolve(fe.MapKey)
+		}
+		r.resolve(fe.Body)
+		return
+	}
+	gen := generators[0]
+	r.resolve(gen.Collection)
+
+	var names []string
+	if gen.Key != nil {
+		names = append(names, gen.Key.Value)
+	}
+	names = append(names, gen.Variable.Value)
+	r.scopes = append(r.scopes, names)
+	if gen.Condition != nil {
+		r.resolve(gen.Condition)
+	}
+	r.resolveFor(fe, generators[1:])
+	r.scopes = r.scopes[:len(r.scopes)-1]
+}
+
+func (r *Resolver) resolveIdentifier(ident *ast.Identifier) {
+	for depth := 0; depth < len(r.scopes); depth++ {
+		scope := r.scopes[len(r.scopes)-1-depth]
+		// Later names win, so `for x, x in ...` binds the value like Memory.Set would.
+		for slot := len(scope) - 1; slot >= 0; slot-- {
+			if scope[slot] == ident.Value {
+				ident.Local, ident.Depth, ident.Slot = true, depth, slot
+				return
+			}
+		}
+	}
+
+	ident.Local, ident.Depth, ident.Slot = false, 0, 0
+	if r.defined[ident.Value] {
+		return
+	}
+	if _, ok := r.globals.Get(ident.Value); ok {
+		return
+	}
+	if _, ok := builtins[ident.Value]; ok {
+		return
+	}
+	r.errors = append(r.errors, fmt.Sprintf("identifier not found: %s", ident.Value))
+}
+
+// resolveMember checks that a member expression names an exported function of an
+// imported module. Module names are globals, so a local of the same name hides the module.
+func (r *Resolver) resolveMember(me *ast.MemberExpression) {
+	r.resolveIdentifier(me.Module)
+	if me.Module.Local {
+		r.errors = append(r.errors, fmt.Sprintf("%s is not a module", me.Module.Value))
+		return
+	}
+	module := moduleNamed(me.Module.Value, r.modules, r.globals)
+	if module == nil {
+		if r.defined[me.Module.Value] {
+			r.errors = append(r.errors, fmt.Sprintf("%s is not a module", me.Module.Value))
+		}
+		return
+	}
+	if _, msg := module.function(me.Module.Value, me.Member.Value); msg != "" {
+		r.errors = append(r.errors, msg)
+	}
+}
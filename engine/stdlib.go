@@ -0,0 +1,29 @@
+package engine
+
+import (
+	"embed"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// stdlib holds the standard library: modules written in Duet on top of the
+// builtins, imported by name as "std/list", "std/str" and so on.
+//
+//go:embed std/*.duet
+var stdlib embed.FS
+
+// isStd reports whether an import path names a standard library module.
+func isStd(name string) bool {
+	return strings.HasPrefix(name, "std/")
+}
+
+// findStd resolves a standard library import path to its file in stdlib.
+func findStd(name string) (string, bool) {
+	p := path.Clean(name)
+	if path.Ext(p) == "" {
+		p += ".duet"
+	}
+	info, err := fs.Stat(stdlib, p)
+	return p, err == nil && !info.IsDir()
+}
@@ -0,0 +1,125 @@
+package engine
+
+import (
+	"iter"
+	"slices"
+	"strings"
+
+	"duet/ast"
+	"duet/object"
+	"duet/token"
+)
+
+// streamClosed is returned by `yield` when the consumer of a generator has stopped
+// reading. It unwinds the generator body like an error and is never shown to users.
+var streamClosed = &object.ErrorObject{Message: "stream closed"}
+
+// isGenerator reports whether body yields values, which makes its supp a generator.
+func isGenerator(body ast.Expression) bool {
+	found := false
+	ast.Inspect(body, func(node ast.Node) bool {
+		if _, ok := node.(*ast.YieldExpression); ok {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// newGenerator returns a stream whose elements are the values yielded by run.
+// run executes on a coroutine that only runs while the consumer waits in Next, so it
+// shares the engine; the call stack and the current yield function are swapped in
+// and out around every switch.
+func (e *ExcutionEngine) newGenerator(run func() object.MemoryObject) *object.StreamObject {
+	seq := func(yield func(object.MemoryObject) bool) {
+		e.yield = yield
+		result := run()
+		if isError(result) && result != streamClosed {
+			yield(result)
+		}
+	}
+	next, stop := iter.Pull(seq)
+
+	var stack []string
+	procs := slices.Clone(e.procs) // a generator started by a proc is bound by its rules
+	var yieldFn func(object.MemoryObject) bool
+	swap := func() {
+		e.callStack, stack = stack, e.callStack
+		e.procs, procs = procs, e.procs
+		e.yield, yieldFn = yieldFn, e.yield
+	}
+	return object.NewStream(func() (object.MemoryObject, bool) {
+		swap()
+		defer swap()
+		return next()
+	}, func() {
+		swap()
+		defer swap()
+		stop()
+	})
+}
+
+// pipeStream applies a pipeline stage to a stream. A proc becomes a lazy stage that
+// transforms each element as it is pulled; a cons consumes the whole stream, one
+// element at a time. Functions whose first parameter is a list get the stream
+// collected into one. Functions whose first parameter is a stream, and builtins, get
+// the stream itself, so handled is false for them.
+func pipeStream(call object.Caller, stream *object.StreamObject, fn object.MemoryObject, args []object.MemoryObject) (result object.MemoryObject, handled bool) {
+	function, ok := fn.(*object.FunctionObject)
+	if !ok || len(function.Parameters) == 0 || function.Parameters[0].Type.Value == "stream" {
+		return nil, false
+	}
+
+	stageArgs := func(el object.MemoryObject) []object.MemoryObject {
+		return append([]object.MemoryObject{el}, args...)
+	}
+
+	if strings.TrimSuffix(function.Parameters[0].Type.Value, "?") == "list" {
+		// collect goes through the engine, which checks the list against MaxAllocSize.
+		list := call(builtins["collect"], stream)
+		if isError(list) {
+			return list, true
+		}
+		return call(function, stageArgs(list)...), true
+	}
+
+	if function.Token.Type == token.CONS {
+		for el := range stream.All() {
+			if isError(el) {
+				return el, true
+			}
+			if result := call(function, stageArgs(el)...); isError(result) {
+				stream.Close()
+				return result, true
+			}
+		}
+		return object.Nil, true
+	}
+
+	return object.NewStream(func() (object.MemoryObject, bool) {
+		el, ok := stream.Next()
+		if !ok || isError(el) {
+			return el, ok
+		}
+		return call(function, stageArgs(el)...), true
+	}, stream.Close), true
+}
+
+func newStreamBuiltins() map[string]*object.BuiltinObject {
+	return map[string]*object.BuiltinObject{
+		"collect": {
+			// The engine reads stream arguments into lists, checking MaxAllocSize as it goes.
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got=%d, want=1", len(args))
+				}
+				switch arg := args[0].(type) {
+				case *object.ListObject:
+					return arg
+				default:
+					return newError("argument to `collect` must be STREAM or LIST, got %s", args[0].Type())
+				}
+			},
+		},
+	}
+}
@@ -0,0 +1,161 @@
+package engine
+
+import (
+	"strings"
+
+	"duet/object"
+)
+
+func newStringBuiltins() map[string]*object.BuiltinObject {
+	return map[string]*object.BuiltinObject{
+		"split": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 2 {
+					return newError("wrong number of arguments. got=%d, want=2", len(args))
+				}
+				s, ok := args[0].(*object.StringObject)
+				if !ok {
+					return newError("first argument to `split` must be STRING, got %s", args[0].Type())
+				}
+				sep, ok := args[1].(*object.StringObject)
+				if !ok {
+					return newError("second argument to `split` must be STRING, got %s", args[1].Type())
+				}
+				parts := strings.Split(s.Value, sep.Value)
+				elements := make([]object.MemoryObject, len(parts))
+				for i, p := range parts {
+					elements[i] = &object.StringObject{Value: p}
+				}
+				return object.NewList(elements)
+			},
+		},
+		"join": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 2 {
+					return newError("wrong number of arguments. got=%d, want=2", len(args))
+				}
+				list, ok := args[0].(*object.ListObject)
+				if !ok {
+					return newError("first argument to `join` must be LIST, got %s", args[0].Type())
+				}
+				sep, ok := args[1].(*object.StringObject)
+				if !ok {
+					return newError("second argument to `join` must be STRING, got %s", args[1].Type())
+				}
+				var parts []string
+				for _, el := range list.All() {
+					s, ok := el.(*object.StringObject)
+					if !ok {
+						return newError("all elements in list for `join` must be STRING, got %s", el.Type())
+					}
+					parts = append(parts, s.Value)
+				}
+				return &object.StringObject{Value: strings.Join(parts, sep.Value)}
+			},
+			Size: func(args ...object.MemoryObject) int {
+				if len(args) != 2 {
+					return 0
+				}
+				list, ok := args[0].(*object.ListObject)
+				sep, isStr := args[1].(*object.StringObject)
+				if !ok || !isStr || list.Len() == 0 {
+					return 0
+				}
+				size := (list.Len() - 1) * len(sep.Value)
+				for _, el := range list.All() {
+					if s, ok := el.(*object.StringObject); ok {
+						size += len(s.Value)
+					}
+				}
+				return size
+			},
+		},
+		"trim": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got=%d, want=1", len(args))
+				}
+				s, ok := args[0].(*object.StringObject)
+				if !ok {
+					return newError("argument to `trim` must be STRING, got %s", args[0].Type())
+				}
+				return &object.StringObject{Value: strings.TrimSpace(s.Value)}
+			},
+		},
+		"upper": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got=%d, want=1", len(args))
+				}
+				s, ok := args[0].(*object.StringObject)
+				if !ok {
+					return newError("argument to `upper` must be STRING, got %s", args[0].Type())
+				}
+				return &object.StringObject{Value: strings.ToUpper(s.Value)}
+			},
+		},
+		"lower": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got=%d, want=1", len(args))
+				}
+				s, ok := args[0].(*object.StringObject)
+				if !ok {
+					return newError("argument to `lower` must be STRING, got %s", args[0].Type())
+				}
+				return &object.StringObject{Value: strings.ToLower(s.Value)}
+			},
+		},
+		"replace": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 3 {
+					return newError("wrong number of arguments. got=%d, want=3", len(args))
+				}
+				s, ok := args[0].(*object.StringObject)
+				if !ok {
+					return newError("first argument to `replace` must be STRING, got %s", args[0].Type())
+				}
+				old, ok := args[1].(*object.StringObject)
+				if !ok {
+					return newError("second argument to `replace` must be STRING, got %s", args[1].Type())
+				}
+				newStr, ok := args[2].(*object.StringObject)
+				if !ok {
+					return newError("third argument to `replace` must be STRING, got %s", args[2].Type())
+				}
+				return &object.StringObject{Value: strings.ReplaceAll(s.Value, old.Value, newStr.Value)}
+			},
+			Size: func(args ...object.MemoryObject) int {
+				if len(args) != 3 {
+					return 0
+				}
+				s, ok1 := args[0].(*object.StringObject)
+				old, ok2 := args[1].(*object.StringObject)
+				newStr, ok3 := args[2].(*object.StringObject)
+				if !ok1 || !ok2 || !ok3 {
+					return 0
+				}
+				return len(s.Value) + strings.Count(s.Value, old.Value)*(len(newStr.Value)-len(old.Value))
+			},
+		},
+		"contains": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 2 {
+					return newError("wrong number of arguments. got=%d, want=2", len(args))
+				}
+				s, ok := args[0].(*object.StringObject)
+				if !ok {
+					return newError("first argument to `contains` must be STRING, got %s", args[0].Type())
+				}
+				sub, ok := args[1].(*object.StringObject)
+				if !ok {
+					return newError("second argument to `contains` must be STRING, got %s", args[1].Type())
+				}
+				if strings.Contains(s.Value, sub.Value) {
+					return object.True
+				}
+				return object.False
+			},
+		},
+	}
+}
@@ -0,0 +1,104 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+
+	"duet/object"
+)
+
+func newTypeBuiltins() map[string]*object.BuiltinObject {
+	return map[string]*object.BuiltinObject{
+		"int": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 1 {
+					return object.NewFail("wrong number of arguments. got=%d, want=1", len(args))
+				}
+				switch arg := args[0].(type) {
+				case *object.StringObject:
+					i, err := strconv.ParseInt(arg.Value, 10, 64)
+					if err != nil {
+						return object.NewFail("could not parse string to int: %s", arg.Value)
+					}
+					return &object.IntegerObject{Value: i}
+				case *object.IntegerObject:
+					return arg
+				case *object.BooleanObject:
+					if arg.Value {
+						return &object.IntegerObject{Value: 1}
+					}
+					return &object.IntegerObject{Value: 0}
+				default:
+					return object.NewFail("argument to `int` not supported, got %s", args[0].Type())
+				}
+			},
+		},
+		"string": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 1 {
+					return object.NewFail("wrong number of arguments. got=%d, want=1", len(args))
+				}
+				return &object.StringObject{Value: args[0].Inspect()}
+			},
+		},
+		"bool": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 1 {
+					return object.NewFail("wrong number of arguments. got=%d, want=1", len(args))
+				}
+				switch arg := args[0].(type) {
+				case *object.BooleanObject:
+					return arg
+				case *object.StringObject:
+					if arg.Value == "true" {
+						return object.True
+					}
+					if arg.Value == "false" {
+						return object.False
+					}
+					return object.NewFail("could not parse string to bool: %s", arg.Value)
+				case *object.IntegerObject:
+					if arg.Value != 0 {
+						return object.True
+					}
+					return object.False
+				default:
+					return object.NewFail("argument to `bool` not supported, got %s", args[0].Type())
+				}
+			},
+		},
+		"type": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 1 {
+					return object.NewFail("wrong number of arguments. got=%d, want=1", len(args))
+				}
+				return &object.StringObject{Value: fmt.Sprintf("%s", args[0].Type())}
+			},
+		},
+		"is_fail": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 1 {
+					return object.NewFail("wrong number of arguments. got=%d, want=1", len(args))
+				}
+				if args[0].Type() == object.FAIL_OBJ {
+					return object.True
+				}
+				return object.False
+			},
+			AcceptsFail: true,
+		},
+		"fail_code": {
+			Fn: func(args ...object.MemoryObject) object.MemoryObject {
+				if len(args) != 1 {
+					return object.NewFail("wrong number of arguments. got=%d, want=1", len(args))
+				}
+				fail, ok := args[0].(*object.FailObject)
+				if !ok {
+					return newError("argument to `fail_code` must be FAIL, got %s", args[0].Type())
+				}
+				return &object.StringObject{Value: fail.Code}
+			},
+			AcceptsFail: true,
+		},
+	}
+}
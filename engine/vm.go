@@ -0,0 +1,626 @@
+package engine
+
+import (
+	"slices"
+
+	"duet/ast"
+	"duet/object"
+	"duet/token"
+)
+
+// frame is the activation record of a function (or of the top-level program) on the VM.
+type frame struct {
+	fn      *object.FunctionObject // nil for the top-level program
+	code    *CompiledFunction
+	ip      int
+	base    int // stack index of the first local; base-1 holds the called function
+	stop    bool
+	proc    bool                     // the frame has run a proc, so it is counted in the engine's procs
+	pending []*object.FunctionObject // functions replaced by tail calls whose return types are still to be checked
+	memos   []memoCall               // @memo calls whose cache receives the frame's result
+}
+
+// globals returns the memory the frame's global names are looked up in: that of the
+// module that defined the function, or the VM's for the top-level program.
+func (f *frame) globals(vm *VM) *object.Memory {
+	if f.fn != nil {
+		return f.fn.Mem
+	}
+	return vm.globals
+}
+
+// accumulatorObject collects the results of a for comprehension on the VM stack.
+type accumulatorObject struct {
+	elements []object.MemoryObject
+	pairs    *object.MapObject
+}
+
+func (a *accumulatorObject) Type() object.MemoryObjectType { return "ACCUMULATOR" }
+func (a *accumulatorObject) Inspect() string               { return "accumulator" }
+
+// iteratorObject walks the list, map or stream of a for generator on the VM stack.
+type iteratorObject struct {
+	list   *object.ListObject
+	pairs  []object.MapPair
+	stream *object.StreamObject
+	index  int
+}
+
+func (it *iteratorObject) Type() object.MemoryObjectType { return "ITERATOR" }
+func (it *iteratorObject) Inspect() string               { return "iterator" }
+
+// VM executes bytecode produced by Compile. Globals live in the same Memory the tree
+// walker uses, so function definitions and REPL sessions work with either backend;
+// locals are addressed by slot on the value stack.
+type VM struct {
+	engine  *ExcutionEngine
+	globals *object.Memory
+	stack   []object.MemoryObject
+	sp      int
+	frames  []*frame
+}
+
+func NewVM(engine *ExcutionEngine, globals *object.Memory) *VM {
+	return &VM{engine: engine, globals: globals, stack: make([]object.MemoryObject, 256)}
+}
+
+// Run executes a compiled program and returns the value of its last statement.
+func (vm *VM) Run(main *CompiledFunction) object.MemoryObject {
+	vm.push(nil) // the top-level frame has no function slot
+	vm.pushFrame(&frame{code: main, base: vm.sp, stop: true})
+	vm.reserve(main.NumLocals)
+	return vm.run()
+}
+
+// runGenerator runs the body of a generator supp on its own stack; the values it
+// yields are delivered by the stream built around it.
+func (vm *VM) runGenerator(fn *object.FunctionObject, code *CompiledFunction, args []object.MemoryObject) object.MemoryObject {
+	vm.push(fn)
+	for _, arg := range args {
+		vm.push(arg)
+	}
+	base := vm.sp - len(args)
+	vm.reserve(code.NumLocals - len(args))
+	vm.engine.callStack = append(vm.engine.callStack, fn.Name.Value)
+	vm.pushFrame(&frame{fn: fn, code: code, base: base, stop: true})
+	return vm.run()
+}
+
+// callFunction is the Caller handed to higher-order builtins; it runs fn to completion.
+func (vm *VM) callFunction(fn object.MemoryObject, args ...object.MemoryObject) object.MemoryObject {
+	vm.push(fn)
+	for _, arg := range args {
+		vm.push(arg)
+	}
+	base := vm.sp - len(args) - 1
+	depth := len(vm.frames)
+	if err := vm.callValue(len(args), false, true); err != nil {
+		vm.sp = base
+		return err
+	}
+	if len(vm.frames) == depth {
+		// Builtins and cached @memo results are already on the stack.
+		return vm.pop()
+	}
+	return vm.run()
+}
+
+func (vm *VM) run() object.MemoryObject {
+	for {
+		if err := vm.engine.step(); err != nil {
+			return vm.unwind(err)
+		}
+
+		f := vm.frames[len(vm.frames)-1]
+		ins := f.code.Instructions
+		op := Opcode(ins[f.ip])
+		f.ip++
+
+		var err object.MemoryObject
+		switch op {
+		case OpConstant:
+			idx := readUint16(ins, f.ip)
+			f.ip += 2
+			vm.push(f.code.Constants[idx].(object.MemoryObject))
+
+		case OpNoValue:
+			vm.push(nil)
+
+		case OpPop:
+			vm.pop()
+
+		case OpSwap:
+			vm.stack[vm.sp-1], vm.stack[vm.sp-2] = vm.stack[vm.sp-2], vm.stack[vm.sp-1]
+
+		case OpGetLocal:
+			slot := readUint16(ins, f.ip)
+			f.ip += 2
+			err = vm.pushVariable(vm.stack[f.base+slot])
+
+		case OpGetGlobal:
+			name := f.code.Constants[readUint16(ins, f.ip)].(string)
+			f.ip += 2
+			if val, ok := f.globals(vm).Get(name); ok {
+				err = vm.pushVariable(val)
+			} else if builtin, ok := builtins[name]; ok {
+				vm.push(builtin)
+			} else {
+				err = newError("identifier not found: %s", name)
+			}
+
+		case OpDefineFunction:
+			def := f.code.Constants[readUint16(ins, f.ip)].(*functionDefinition)
+			f.ip += 2
+			fn := newFunction(def.Statement, vm.globals)
+			fn.Code = def.Code
+			vm.globals.Set(fn.Name.Value, fn)
+
+		case OpImport:
+			stmt := f.code.Constants[readUint16(ins, f.ip)].(*ast.ImportStatement)
+			f.ip += 2
+			err = vm.engine.importModule(stmt, vm.globals)
+
+		case OpMember:
+			node := f.code.Constants[readUint16(ins, f.ip)].(*ast.MemberExpression)
+			f.ip += 2
+			val := member(vm.pop(), node.Module.Value, node.Member.Value)
+			if isError(val) {
+				err = val
+			} else {
+				err = vm.pushVariable(val)
+			}
+
+		case OpFail:
+			message := f.code.Constants[readUint16(ins, f.ip)].(string)
+			f.ip += 2
+			vm.push(&object.FailObject{Message: message})
+
+		case OpBang:
+			vm.push(evalBangOperatorExpression(vm.pop()))
+
+		case OpMinus:
+			err = vm.pushResult(evalMinusPrefixOperatorExpression(vm.pop()))
+
+		case OpInfix:
+			operator := infixOperators[ins[f.ip]]
+			f.ip++
+			right := vm.pop()
+			left := vm.pop()
+			if repeatErr := vm.engine.checkRepeatSize(operator, left, right); repeatErr != nil {
+				err = repeatErr
+			} else {
+				err = vm.pushResult(vm.engine.checkSize(evalInfixExpression(operator, left, right)))
+			}
+
+		case OpIndex:
+			index := vm.pop()
+			left := vm.pop()
+			err = vm.pushResult(evalIndexExpression(left, index))
+
+		case OpJump:
+			f.ip = readUint16(ins, f.ip)
+
+		case OpJumpIfFalse:
+			target := readUint16(ins, f.ip)
+			f.ip += 2
+			if !isTruthy(vm.pop()) {
+				f.ip = target
+			}
+
+		case OpList:
+			count := readUint16(ins, f.ip)
+			f.ip += 2
+			elements := make([]object.MemoryObject, count)
+			copy(elements, vm.stack[vm.sp-count:vm.sp])
+			vm.sp -= count
+			err = vm.pushResult(vm.engine.checkSize(object.NewList(elements)))
+
+		case OpMap:
+			count := readUint16(ins, f.ip)
+			f.ip += 2
+			pairs := object.NewMap()
+			start := vm.sp - 2*count
+			for i := start; i < vm.sp; i += 2 {
+				key, ok := vm.stack[i].(object.Hashable)
+				if !ok {
+					err = newError("unusable as hash key: %s", vm.stack[i].Type())
+					break
+				}
+				pairs = pairs.Set(key.HashKey(), object.MapPair{Key: vm.stack[i], Value: vm.stack[i+1]})
+			}
+			vm.sp = start
+			if err == nil {
+				vm.push(pairs)
+			}
+
+		case OpCall, OpTailCall:
+			argc := int(ins[f.ip])
+			f.ip++
+			err = vm.callValue(argc, op == OpTailCall, false)
+
+		case OpPipeCall:
+			argc := int(ins[f.ip])
+			tail := ins[f.ip+1] == 1
+			f.ip += 2
+			// A stream flowing into a proc or cons is handled element by element.
+			if stream, ok := vm.stack[vm.sp-argc].(*object.StreamObject); ok {
+				fn := vm.stack[vm.sp-1-argc]
+				rest := slices.Clone(vm.stack[vm.sp-argc+1 : vm.sp])
+				if result, handled := pipeStream(vm.callFunction, stream, fn, rest); handled {
+					vm.sp -= argc + 1
+					err = vm.pushResult(result)
+					break
+				}
+			}
+			err = vm.callValue(argc, tail, false)
+
+		case OpParallelPipe:
+			n := int(ins[f.ip])
+			f.ip++
+			stages := make([]pipeStage, n)
+			start := vm.sp - 2*n
+			for i := range stages {
+				stages[i] = pipeStage{fn: vm.stack[start+2*i], args: vm.stack[start+2*i+1].(*object.ListObject).Elements()}
+			}
+			vm.sp = start
+			source := vm.pop()
+			err = vm.pushResult(vm.engine.runParallel(source, stages))
+
+		case OpSpawn:
+			argc := int(ins[f.ip])
+			f.ip++
+			args := slices.Clone(vm.stack[vm.sp-argc : vm.sp])
+			fn := vm.stack[vm.sp-argc-1]
+			vm.sp -= argc + 1
+			err = vm.pushResult(vm.engine.spawn(fn, args))
+
+		case OpSelect:
+			info := f.code.Constants[readUint16(ins, f.ip)].(*selectInfo)
+			f.ip += 2
+			operands := make([][]object.MemoryObject, len(info.Cases))
+			start := vm.sp
+			for i := len(info.Cases) - 1; i >= 0; i-- {
+				start -= len(info.Cases[i].Call.Arguments)
+				operands[i] = slices.Clone(vm.stack[start : start+len(info.Cases[i].Call.Arguments)])
+			}
+			vm.sp = start
+			cases, selectErr := selectCases(info.Cases, operands)
+			if selectErr != nil {
+				err = selectErr
+				break
+			}
+			index, value := vm.engine.scheduler().choose(cases, !info.HasDefault)
+			switch {
+			case index < 0:
+				f.ip = info.Default
+			case isError(value):
+				err = value
+			default:
+				if slot := info.Slots[index]; slot != noSlot {
+					vm.stack[f.base+slot] = value
+				}
+				f.ip = info.Targets[index]
+			}
+
+		case OpYield:
+			err = vm.pushResult(vm.engine.yieldValue(vm.pop()))
+
+		case OpPipeSource:
+			// As in evalPipeline, a zero-argument function or any builtin on the
+			// left side is invoked and its result is fed into the pipeline.
+			switch fn := vm.stack[vm.sp-1].(type) {
+			case *object.FunctionObject:
+				if len(fn.Parameters) == 0 {
+					err = vm.callValue(0, false, false)
+				}
+			case *object.BuiltinObject:
+				err = vm.callValue(0, false, false)
+			}
+
+		case OpReturn:
+			result := vm.pop()
+			if f.fn != nil && !f.fn.Generator {
+				if returnValue, ok := result.(*object.ReturnValueObject); ok {
+					result = returnValue.Value
+				}
+				result = checkReturnValue(f.fn, result)
+				for i := len(f.pending) - 1; i >= 0 && !isError(result); i-- {
+					if f.pending[i] != f.fn {
+						result = checkReturnValue(f.pending[i], result)
+					}
+				}
+				if isError(result) {
+					return vm.unwind(result)
+				}
+				for _, m := range f.memos {
+					m.cache.put(m.key, result)
+				}
+			}
+			vm.popFrame()
+			vm.sp = f.base - 1
+			if f.stop {
+				return result
+			}
+			vm.push(result)
+
+		case OpAccumulator:
+			acc := &accumulatorObject{}
+			if ins[f.ip] == 1 {
+				acc.pairs = object.NewMap()
+			}
+			f.ip++
+			vm.push(acc)
+
+		case OpAccumulate:
+			depth := int(ins[f.ip])
+			f.ip++
+			value := vm.pop()
+			acc := vm.stack[vm.sp-1-depth].(*accumulatorObject)
+			acc.elements = append(acc.elements, value)
+			if max := vm.engine.Options.MaxAllocSize; max > 0 && len(acc.elements) > max {
+				err = vm.engine.allocError(len(acc.elements))
+			}
+
+		case OpAccumulatePair:
+			depth := int(ins[f.ip])
+			f.ip++
+			value := vm.pop()
+			key := vm.pop()
+			acc := vm.stack[vm.sp-1-depth].(*accumulatorObject)
+			if hashKey, ok := key.(object.Hashable); ok {
+				acc.pairs = acc.pairs.Set(hashKey.HashKey(), object.MapPair{Key: key, Value: value})
+			} else {
+				err = newError("unusable as hash key: %s", key.Type())
+			}
+
+		case OpFinish:
+			acc := vm.pop().(*accumulatorObject)
+			if acc.pairs != nil {
+				vm.push(acc.pairs)
+			} else {
+				vm.push(object.NewList(acc.elements))
+			}
+
+		case OpIterStart:
+			switch coll := vm.pop().(type) {
+			case *object.ListObject:
+				vm.push(&iteratorObject{list: coll})
+			case *object.MapObject:
+				vm.push(&iteratorObject{pairs: coll.SortedPairs()})
+			case *object.StreamObject:
+				vm.push(&iteratorObject{stream: coll})
+			default:
+				err = newError("for loop must iterate over a list, map or stream, got %s", coll.Type())
+			}
+
+		case OpIterNext:
+			keySlot := readUint16(ins, f.ip)
+			varSlot := readUint16(ins, f.ip+2)
+			end := readUint16(ins, f.ip+4)
+			f.ip += 6
+			it := vm.stack[vm.sp-1].(*iteratorObject)
+			var key, value object.MemoryObject
+			switch {
+			case it.stream != nil:
+				el, ok := it.stream.Next()
+				if !ok {
+					f.ip = end
+					continue
+				}
+				if isError(el) {
+					err = el
+					break
+				}
+				key, value = &object.IntegerObject{Value: int64(it.index)}, el
+			case it.list != nil && it.index < it.list.Len():
+				key, value = &object.IntegerObject{Value: int64(it.index)}, it.list.At(it.index)
+			case it.list == nil && it.index < len(it.pairs):
+				// With a single variable, map iteration binds the key.
+				key, value = it.pairs[it.index].Key, it.pairs[it.index].Key
+				if keySlot != noSlot {
+					value = it.pairs[it.index].Value
+				}
+			default:
+				f.ip = end
+				continue
+			}
+			if err != nil {
+				break
+			}
+			it.index++
+			if keySlot != noSlot {
+				vm.stack[f.base+keySlot] = key
+			}
+			vm.stack[f.base+varSlot] = value
+
+		default:
+			err = newError("vm: unknown opcode %d", op)
+		}
+
+		if err != nil {
+			return vm.unwind(err)
+		}
+	}
+}
+
+// callValue calls the function below the topmost argc values. User functions get a new
+// frame (or reuse the current one for tail calls); builtins run immediately.
+// stop marks a frame whose return hands control back to the Go caller of run.
+func (vm *VM) callValue(argc int, tail, stop bool) object.MemoryObject {
+	callee := vm.stack[vm.sp-1-argc]
+	args := vm.stack[vm.sp-argc : vm.sp]
+
+	switch fn := callee.(type) {
+	case *object.FunctionObject:
+		if err := checkArguments(fn, args); err != nil {
+			return err
+		}
+		if err := vm.engine.checkEffect(fn); err != nil {
+			return err
+		}
+		var memos []memoCall
+		if cache := memoOf(fn); cache != nil {
+			if err := vm.engine.checkMemo(fn, cache); err != nil {
+				return err
+			}
+			if key, ok := memoKey(args); ok {
+				if cached, ok := cache.get(key); ok {
+					vm.sp -= argc + 1
+					vm.push(cached)
+					return nil
+				}
+				memos = append(memos, memoCall{cache: cache, key: key})
+			}
+		}
+		code, err := vm.codeFor(fn)
+		if err != nil {
+			return err
+		}
+		if fn.Generator {
+			args := slices.Clone(args)
+			vm.sp -= argc + 1
+			vm.push(vm.engine.newGenerator(func() object.MemoryObject {
+				return NewVM(vm.engine, vm.globals).runGenerator(fn, code, args)
+			}))
+			return nil
+		}
+
+		if current := vm.currentFrame(); tail && current.fn != nil {
+			// Reuse the current frame: move the arguments down over the old locals.
+			copy(vm.stack[current.base:], args)
+			vm.sp = current.base + argc
+			vm.reserve(code.NumLocals - argc)
+			vm.stack[current.base-1] = fn
+			if !slices.Contains(current.pending, current.fn) {
+				current.pending = append(current.pending, current.fn)
+			}
+			current.memos = append(current.memos, memos...)
+			current.fn, current.code, current.ip = fn, code, 0
+			vm.engine.callStack[len(vm.engine.callStack)-1] = fn.Name.Value
+			if fn.Token.Type == token.PROC && !current.proc {
+				current.proc = true
+				vm.engine.procs = append(vm.engine.procs, fn.Name.Value)
+			}
+			return nil
+		}
+
+		if len(vm.engine.callStack) >= vm.engine.maxDepth() {
+			return vm.engine.stackOverflowError(fn)
+		}
+		vm.engine.callStack = append(vm.engine.callStack, fn.Name.Value)
+		base := vm.sp - argc
+		vm.reserve(code.NumLocals - argc)
+		f := &frame{fn: fn, code: code, base: base, stop: stop, memos: memos, proc: fn.Token.Type == token.PROC}
+		if f.proc {
+			vm.engine.procs = append(vm.engine.procs, fn.Name.Value)
+		}
+		vm.pushFrame(f)
+		return nil
+
+	case *object.BuiltinObject:
+		result := vm.engine.applyBuiltin(fn, slices.Clone(args), vm.callFunction)
+		vm.sp -= argc + 1
+		return vm.pushResult(result)
+
+	default:
+		return newError("not a function: %s", callee.Type())
+	}
+}
+
+// pushVariable pushes a variable's value, invoking it first when it is a zero-argument supplier.
+func (vm *VM) pushVariable(val object.MemoryObject) object.MemoryObject {
+	vm.push(val)
+	if fn, ok := val.(*object.FunctionObject); ok && fn.Token.Type == token.SUPP && len(fn.Parameters) == 0 {
+		return vm.callValue(0, false, false)
+	}
+	return nil
+}
+
+// codeFor returns the bytecode of fn, compiling functions defined by the tree walker on first use.
+func (vm *VM) codeFor(fn *object.FunctionObject) (*CompiledFunction, object.MemoryObject) {
+	if code, ok := fn.Code.(*CompiledFunction); ok {
+		return code, nil
+	}
+	code, err := compileFunction(fn.Parameters, fn.Body)
+	if err != nil {
+		return nil, newError("%s", err)
+	}
+	fn.Code = code
+	return code, nil
+}
+
+// unwind drops every frame up to and including the innermost stop frame and returns err.
+func (vm *VM) unwind(err object.MemoryObject) object.MemoryObject {
+	for {
+		f := vm.popFrame()
+		vm.sp = f.base - 1
+		if f.stop {
+			return err
+		}
+	}
+}
+
+// currentFrame returns the innermost frame; a VM that only runs calls made by
+// builtins or parallel stages starts without one.
+func (vm *VM) currentFrame() *frame {
+	if len(vm.frames) == 0 {
+		return &frame{}
+	}
+	return vm.frames[len(vm.frames)-1]
+}
+
+func (vm *VM) pushFrame(f *frame) {
+	vm.frames = append(vm.frames, f)
+}
+
+func (vm *VM) popFrame() *frame {
+	f := vm.frames[len(vm.frames)-1]
+	vm.frames = vm.frames[:len(vm.frames)-1]
+	if f.fn != nil {
+		vm.engine.callStack = vm.engine.callStack[:len(vm.engine.callStack)-1]
+	}
+	if f.proc {
+		vm.engine.procs = vm.engine.procs[:len(vm.engine.procs)-1]
+	}
+	return f
+}
+
+// pushResult pushes obj, or returns it when it is an error that must abort execution.
+func (vm *VM) pushResult(obj object.MemoryObject) object.MemoryObject {
+	if isError(obj) {
+		return obj
+	}
+	vm.push(obj)
+	return nil
+}
+
+func (vm *VM) push(obj object.MemoryObject) {
+	if vm.sp == len(vm.stack) {
+		vm.stack = append(vm.stack, make([]object.MemoryObject, len(vm.stack))...)
+	}
+	vm.stack[vm.sp] = obj
+	vm.sp++
+}
+
+func (vm *VM) pop() object.MemoryObject {
+	vm.sp--
+	obj := vm.stack[vm.sp]
+	vm.stack[vm.sp] = nil
+	return obj
+}
+
+// reserve pushes n empty local slots.
+func (vm *VM) reserve(n int) {
+	for i := 0; i < n; i++ {
+		vm.push(nil)
+	}
+}
+
+// runVM compiles the engine's program and executes it on the bytecode VM.
+func (e *ExcutionEngine) runVM() object.MemoryObject {
+	main, err := Compile(e.Program)
+	if err != nil {
+		return newError("%s", err)
+	}
+	return NewVM(e, e.Memory).Run(main)
+}
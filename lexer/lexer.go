@@ -1,4 +1,9 @@
-package main
+// Package lexer turns Duet source text into tokens.
+package lexer
+
+import (
+	"duet/token"
+)
 
 type Lexer struct {
 	input        string
@@ -30,8 +35,8 @@ func (l *Lexer) peekChar() byte {
 	return l.input[l.readPosition]
 }
 
-func (l *Lexer) NextToken() Token {
-	var tok Token
+func (l *Lexer) NextToken() token.Token {
+	var tok token.Token
 
 	l.skipWhitespace()
 
@@ -41,75 +46,93 @@ func (l *Lexer) NextToken() Token {
 			ch := l.ch
 			l.readChar()
 			literal := string(ch) + string(l.ch)
-			tok = Token{Type: EQ, Literal: literal}
+			tok = token.Token{Type: token.EQ, Literal: literal}
 		} else {
-			tok = newToken(ASSIGN, l.ch)
+			tok = newToken(token.ASSIGN, l.ch)
 		}
 	case '+':
-		tok = newToken(PLUS, l.ch)
+		tok = newToken(token.PLUS, l.ch)
 	case '-':
 		if l.peekChar() == '>' {
 			ch := l.ch
 			l.readChar()
 			literal := string(ch) + string(l.ch)
-			tok = Token{Type: ARROW, Literal: literal}
+			tok = token.Token{Type: token.ARROW, Literal: literal}
 		} else {
-			tok = newToken(MINUS, l.ch)
+			tok = newToken(token.MINUS, l.ch)
 		}
 	case '!':
 		if l.peekChar() == '=' {
 			ch := l.ch
 			l.readChar()
 			literal := string(ch) + string(l.ch)
-			tok = Token{Type: NOT_EQ, Literal: literal}
+			tok = token.Token{Type: token.NOT_EQ, Literal: literal}
 		} else {
-			tok = newToken(BANG, l.ch)
+			tok = newToken(token.BANG, l.ch)
 		}
 	case '/':
-		tok = newToken(SLASH, l.ch)
+		tok = newToken(token.SLASH, l.ch)
 	case '*':
-		tok = newToken(ASTERISK, l.ch)
+		tok = newToken(token.ASTERISK, l.ch)
 	case '%':
-		tok = newToken(MODULO, l.ch)
+		tok = newToken(token.MODULO, l.ch)
 	case '<':
-		tok = newToken(LT, l.ch)
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = token.Token{Type: token.LE, Literal: "<="}
+		} else {
+			tok = newToken(token.LT, l.ch)
+		}
 	case '>':
-		tok = newToken(GT, l.ch)
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = token.Token{Type: token.GE, Literal: ">="}
+		} else {
+			tok = newToken(token.GT, l.ch)
+		}
 	case '|':
-		if l.peekChar() == '>' {
+		if l.peekChar() == '|' && l.readPosition+1 < len(l.input) && l.input[l.readPosition+1] == '>' {
+			l.readChar()
+			l.readChar()
+			tok = token.Token{Type: token.PARALLEL_PIPELINE, Literal: "||>"}
+		} else if l.peekChar() == '>' {
 			ch := l.ch
 			l.readChar()
 			literal := string(ch) + string(l.ch)
-			tok = Token{Type: PIPELINE, Literal: literal}
+			tok = token.Token{Type: token.PIPELINE, Literal: literal}
 		} else {
-			tok = newToken(ILLEGAL, l.ch)
+			tok = newToken(token.ILLEGAL, l.ch)
 		}
 	case ':':
-		tok = newToken(COLON, l.ch)
+		tok = newToken(token.COLON, l.ch)
 	case ',':
-		tok = newToken(COMMA, l.ch)
+		tok = newToken(token.COMMA, l.ch)
 	case '(':
-		tok = newToken(LPAREN, l.ch)
+		tok = newToken(token.LPAREN, l.ch)
 	case ')':
-		tok = newToken(RPAREN, l.ch)
+		tok = newToken(token.RPAREN, l.ch)
 	case '{':
-		tok = newToken(LBRACE, l.ch)
+		tok = newToken(token.LBRACE, l.ch)
 	case '}':
-		tok = newToken(RBRACE, l.ch)
+		tok = newToken(token.RBRACE, l.ch)
 	case '[':
-		tok = newToken(LBRACKET, l.ch)
+		tok = newToken(token.LBRACKET, l.ch)
 	case ']':
-		tok = newToken(RBRACKET, l.ch)
+		tok = newToken(token.RBRACKET, l.ch)
+	case '@':
+		tok = newToken(token.AT, l.ch)
+	case '.':
+		tok = newToken(token.DOT, l.ch)
 	case '"':
-		tok.Type = STRING
+		tok.Type = token.STRING
 		tok.Literal = l.readString()
 	case 0:
 		tok.Literal = ""
-		tok.Type = EOF
+		tok.Type = token.EOF
 	default:
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
-			tok.Type = LookupIdent(tok.Literal)
+			tok.Type = token.LookupIdent(tok.Literal)
 			return tok
 		} else if isDigit(l.ch) {
 			literal := l.readNumber()
@@ -121,14 +144,14 @@ func (l *Lexer) NextToken() Token {
 				}
 			}
 			if isFloat {
-				tok.Type = FLOAT
+				tok.Type = token.FLOAT
 			} else {
-				tok.Type = INT
+				tok.Type = token.INT
 			}
 			tok.Literal = literal
 			return tok
 		} else {
-			tok = newToken(ILLEGAL, l.ch)
+			tok = newToken(token.ILLEGAL, l.ch)
 		}
 	}
 
@@ -136,9 +159,19 @@ func (l *Lexer) NextToken() Token {
 	return tok
 }
 
+// skipWhitespace skips whitespace and // comments, which run to the end of the line.
 func (l *Lexer) skipWhitespace() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
-		l.readChar()
+	for {
+		switch {
+		case l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r':
+			l.readChar()
+		case l.ch == '/' && l.peekChar() == '/':
+			for l.ch != '\n' && l.ch != 0 {
+				l.readChar()
+			}
+		default:
+			return
+		}
 	}
 }
 
@@ -177,6 +210,6 @@ func isDigit(ch byte) bool {
 	return '0' <= ch && ch <= '9'
 }
 
-func newToken(tokenType TokenType, ch byte) Token {
-	return Token{Type: tokenType, Literal: string(ch)}
+func newToken(tokenType token.TokenType, ch byte) token.Token {
+	return token.Token{Type: tokenType, Literal: string(ch)}
 }
@@ -2,45 +2,57 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
+
+	"duet/engine"
+	"duet/object"
 )
 
 const VERSION = "0.1"
 const PROMPT = "? "
 
-func FileExecute(filename string) {
-	file, err := os.ReadFile(filename)
-	if err != nil {
-		fmt.Println("Error reading file:", err)
-		return
+// execute compiles source against e's globals and runs it, printing the result.
+func execute(e *engine.ExcutionEngine, source string, compile engine.CompileOptions, out io.Writer) {
+	compile.Globals = e.Memory
+	script, err := engine.CompileScript(source, compile)
+	var evaluated object.MemoryObject
+	if err == nil {
+		evaluated, err = e.Exec(context.Background(), script)
 	}
 
-	l := New(string(file))
-	p := NewParser(l)
-
-	program := p.ParseProgram()
-
-	if len(p.Errors()) != 0 {
-		printParserErrors(os.Stdout, p.Errors())
-		return
+	var parseErr *engine.ParseError
+	switch {
+	case errors.As(err, &parseErr):
+		printParserErrors(out, parseErr.Errors)
+	case err != nil:
+		io.WriteString(out, "ERROR: "+err.Error()+"\n")
+	case evaluated != nil:
+		io.WriteString(out, evaluated.Inspect()+"\n")
 	}
+}
 
-	engine := NewExcutionEngine(program, nil)
-	evaluated := engine.Run()
-
-	if evaluated != nil {
-		fmt.Println(evaluated.Inspect())
+func FileExecute(filename string, compile engine.CompileOptions, options engine.EngineOptions) {
+	file, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Println("Error reading file:", err)
+		return
 	}
+	compile.Path = filename
+	execute(engine.New(options), string(file), compile, os.Stdout)
 }
 
-func Repl(in io.Reader, out io.Writer) {
+func Repl(in io.Reader, out io.Writer, compile engine.CompileOptions, options engine.EngineOptions) {
 	fmt.Printf("Duet version %s. Ctrl-C to exit.\n", VERSION)
 
 	scanner := bufio.NewScanner(in)
-	memory := NewMemory()
+	e := engine.New(options)
 
 	for {
 		fmt.Fprint(out, PROMPT)
@@ -48,25 +60,25 @@ func Repl(in io.Reader, out io.Writer) {
 		if !scanned {
 			return
 		}
+		execute(e, scanner.Text(), compile, out)
+	}
+}
 
-		line := scanner.Text()
-		l := New(line)
-		p := NewParser(l)
-
-		program := p.ParseProgram()
-		if len(p.Errors()) != 0 {
-			printParserErrors(out, p.Errors())
-			continue
-		}
+// dirs is a flag that may be repeated and takes comma-separated directories.
+type dirs []string
 
-		engine := NewExcutionEngine(program, memory)
-		evaluated := engine.Run()
+func (d *dirs) String() string { return strings.Join(*d, ",") }
 
-		if evaluated != nil {
-			io.WriteString(out, evaluated.Inspect())
-			io.WriteString(out, "\n")
+func (d *dirs) Set(value string) error {
+	for _, dir := range strings.Split(value, ",") {
+		if dir != "" {
+			*d = append(*d, dir)
 		}
 	}
+	if *d == nil {
+		*d = dirs{}
+	}
+	return nil
 }
 
 func printParserErrors(out io.Writer, errors []string) {
@@ -79,16 +91,54 @@ func main() {
 	var version bool
 	flag.BoolVar(&version, "version", false, "print Duet version")
 	flag.BoolVar(&version, "v", false, "print Duet version (shorthand)")
+
+	var options engine.EngineOptions
+	flag.IntVar(&options.MaxDepth, "max-depth", engine.MAX_CALL_DEPTH, "maximum function call depth")
+	flag.Int64Var(&options.MaxSteps, "max-steps", 0, "maximum evaluation steps per run (0 = unlimited)")
+	flag.DurationVar(&options.Timeout, "timeout", 0, "maximum wall-clock time per run, e.g. 5s (0 = unlimited)")
+	flag.IntVar(&options.MaxAllocSize, "max-alloc", 0, "maximum list length or string size in bytes (0 = unlimited)")
+	flag.BoolVar(&options.UseVM, "vm", false, "run programs on the bytecode VM instead of the tree-walking evaluator")
+	var compile engine.CompileOptions
+	flag.BoolVar(&compile.Optimize, "optimize", false, "fold constants, inline trivial procs and fuse for/map stages before running")
+	var dumpAST bool
+	flag.BoolVar(&dumpAST, "dump-ast", false, "print the program after optimization")
+	flag.Var((*dirs)(&compile.ModulePath), "path", "search these directories for imported modules after the importing file's own (comma-separated, repeatable; DUETPATH is searched last)")
+	var sandbox object.Sandbox
+	var sandboxAll bool
+	flag.Var((*dirs)(&sandbox.ReadRoots), "allow-read", "only let scripts read files under these directories (comma-separated, repeatable)")
+	flag.Var((*dirs)(&sandbox.WriteRoots), "allow-write", "only let scripts write files under these directories (comma-separated, repeatable)")
+	flag.BoolVar(&sandbox.NoStdin, "no-stdin", false, "do not let scripts read standard input")
+	flag.BoolVar(&sandbox.NoNetwork, "no-network", false, "do not let scripts use the network")
+	flag.BoolVar(&sandbox.NoExec, "no-exec", false, "do not let scripts run programs")
+	flag.BoolVar(&sandboxAll, "sandbox", false, "deny scripts everything not allowed by -allow-read or -allow-write")
 	flag.Parse()
 
 	if version {
 		fmt.Printf("Duet version %s\n", VERSION)
 		return
 	}
+	if dumpAST {
+		compile.Dump = os.Stdout
+	}
+	compile.ModulePath = append(compile.ModulePath, filepath.SplitList(os.Getenv("DUETPATH"))...)
+	if sandboxAll {
+		if sandbox.ReadRoots == nil {
+			sandbox.ReadRoots = []string{}
+		}
+		if sandbox.WriteRoots == nil {
+			sandbox.WriteRoots = []string{}
+		}
+		sandbox.NoStdin, sandbox.NoNetwork, sandbox.NoExec = true, true, true
+	}
+	if sandbox.ReadRoots != nil || sandbox.WriteRoots != nil || sandbox.NoStdin || sandbox.NoNetwork || sandbox.NoExec {
+		options.IO = object.StdIO()
+		options.IO.Sandbox = &sandbox
+		compile.FS = options.IO
+	}
 
 	if flag.NArg() > 0 {
-		FileExecute(flag.Arg(0))
+		FileExecute(flag.Arg(0), compile, options)
 	} else {
-		Repl(os.Stdin, os.Stdout)
+		Repl(os.Stdin, os.Stdout, compile, options)
 	}
 }
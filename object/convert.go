@@ -0,0 +1,253 @@
+package object
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// FromGo converts a Go value to a Duet value. Booleans, integers, floats and
+// strings map to the scalar types, nil to nil, slices and arrays to lists and maps
+// with string, integer or boolean keys to maps. A MemoryObject is returned as is.
+func FromGo(value any) (MemoryObject, error) {
+	switch v := value.(type) {
+	case nil:
+		return Nil, nil
+	case MemoryObject:
+		return v, nil
+	case bool:
+		if v {
+			return True, nil
+		}
+		return False, nil
+	case string:
+		return &StringObject{Value: v}, nil
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Bool:
+		return FromGo(rv.Bool())
+	case reflect.String:
+		return FromGo(rv.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &IntegerObject{Value: rv.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if rv.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("cannot convert %d to INTEGER: out of range", rv.Uint())
+		}
+		return &IntegerObject{Value: int64(rv.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return &FloatObject{Value: rv.Float()}, nil
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return Nil, nil
+		}
+		return FromGo(rv.Elem().Interface())
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return NewList(nil), nil
+		}
+		elements := make([]MemoryObject, rv.Len())
+		for i := range elements {
+			el, err := FromGo(rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = el
+		}
+		return NewList(elements), nil
+	case reflect.Map:
+		m := NewMap()
+		iter := rv.MapRange()
+		for iter.Next() {
+			key, err := FromGo(iter.Key().Interface())
+			if err != nil {
+				return nil, err
+			}
+			hashable, ok := key.(Hashable)
+			if !ok {
+				return nil, fmt.Errorf("cannot use %s as a map key", key.Type())
+			}
+			val, err := FromGo(iter.Value().Interface())
+			if err != nil {
+				return nil, err
+			}
+			m = m.Set(hashable.HashKey(), MapPair{Key: key, Value: val})
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("cannot convert Go value of type %T to a Duet value", value)
+}
+
+// ToGo converts a Duet value to a Go value: int64, float64, string, bool, nil,
+// []any for lists and, for maps, map[string]any when every key is a string and
+// map[any]any otherwise. A FAIL is returned as its *FailObject and an ERROR as the
+// error. Functions, streams and channels have no Go counterpart.
+func ToGo(obj MemoryObject) (any, error) {
+	switch obj := obj.(type) {
+	case nil, *NilObject:
+		return nil, nil
+	case *IntegerObject:
+		return obj.Value, nil
+	case *FloatObject:
+		return obj.Value, nil
+	case *StringObject:
+		return obj.Value, nil
+	case *BooleanObject:
+		return obj.Value, nil
+	case *FailObject:
+		return obj, nil
+	case *ErrorObject:
+		return nil, obj
+	case *ListObject:
+		elements := make([]any, 0, obj.Len())
+		for _, el := range obj.All() {
+			v, err := ToGo(el)
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, v)
+		}
+		return elements, nil
+	case *MapObject:
+		return mapToGo(obj)
+	}
+	return nil, fmt.Errorf("cannot convert %s to a Go value", obj.Type())
+}
+
+func mapToGo(m *MapObject) (any, error) {
+	strs := map[string]any{}
+	var anys map[any]any
+	for _, pair := range m.All() {
+		v, err := ToGo(pair.Value)
+		if err != nil {
+			return nil, err
+		}
+		if key, ok := pair.Key.(*StringObject); ok && anys == nil {
+			strs[key.Value] = v
+			continue
+		}
+		if anys == nil {
+			anys = make(map[any]any, m.Len())
+			for k, v := range strs {
+				anys[k] = v
+			}
+		}
+		key, err := ToGo(pair.Key)
+		if err != nil {
+			return nil, err
+		}
+		anys[key] = v
+	}
+	if anys != nil {
+		return anys, nil
+	}
+	return strs, nil
+}
+
+// ToGoType converts obj to a value of the Go type t, e.g. for a parameter of a host
+// function. A value whose own type is assignable to t, such as any MemoryObject for a
+// MemoryObject parameter, is passed as is; an interface type such as any gets ToGo(obj).
+func ToGoType(obj MemoryObject, t reflect.Type) (reflect.Value, error) {
+	if reflect.TypeOf(obj).AssignableTo(t) {
+		return reflect.ValueOf(obj), nil
+	}
+
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Interface:
+		value, err := ToGo(obj)
+		if err != nil {
+			return v, err
+		}
+		if value == nil {
+			return v, nil
+		}
+		if !reflect.TypeOf(value).AssignableTo(t) {
+			break
+		}
+		v.Set(reflect.ValueOf(value))
+		return v, nil
+	case reflect.Bool:
+		if b, ok := obj.(*BooleanObject); ok {
+			v.SetBool(b.Value)
+			return v, nil
+		}
+	case reflect.String:
+		if s, ok := obj.(*StringObject); ok {
+			v.SetString(s.Value)
+			return v, nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, ok := obj.(*IntegerObject); ok {
+			if v.OverflowInt(i.Value) {
+				return v, fmt.Errorf("%d does not fit in %s", i.Value, t)
+			}
+			v.SetInt(i.Value)
+			return v, nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if i, ok := obj.(*IntegerObject); ok {
+			if i.Value < 0 || v.OverflowUint(uint64(i.Value)) {
+				return v, fmt.Errorf("%d does not fit in %s", i.Value, t)
+			}
+			v.SetUint(uint64(i.Value))
+			return v, nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, ok := GetFloat(obj); ok {
+			v.SetFloat(f)
+			return v, nil
+		}
+	case reflect.Slice:
+		if l, ok := obj.(*ListObject); ok {
+			v = reflect.MakeSlice(t, 0, l.Len())
+			for _, el := range l.All() {
+				ev, err := ToGoType(el, t.Elem())
+				if err != nil {
+					return v, err
+				}
+				v = reflect.Append(v, ev)
+			}
+			return v, nil
+		}
+	case reflect.Map:
+		if m, ok := obj.(*MapObject); ok {
+			v = reflect.MakeMapWithSize(t, m.Len())
+			for _, pair := range m.All() {
+				kv, err := ToGoType(pair.Key, t.Key())
+				if err != nil {
+					return v, err
+				}
+				ev, err := ToGoType(pair.Value, t.Elem())
+				if err != nil {
+					return v, err
+				}
+				v.SetMapIndex(kv, ev)
+			}
+			return v, nil
+		}
+	}
+	return v, fmt.Errorf("must be %s, got %s", duetTypeOf(t), obj.Type())
+}
+
+// duetTypeOf names the Duet type ToGoType accepts for t.
+func duetTypeOf(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return BOOLEAN_OBJ
+	case reflect.String:
+		return STRING_OBJ
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return INTEGER_OBJ
+	case reflect.Float32, reflect.Float64:
+		return FLOAT_OBJ
+	case reflect.Slice:
+		return LIST_OBJ
+	case reflect.Map:
+		return MAP_OBJ
+	}
+	return t.String()
+}
@@ -0,0 +1,205 @@
+package object
+
+import (
+	"hash/fnv"
+	"iter"
+	"math/bits"
+)
+
+// hamtNode is a node of a persistent hash array mapped trie. Each level consumes
+// hamtBits of the key hash; entries are stored compactly and located through the
+// bitmap. Below the last level, keys with identical hashes are kept in a flat list.
+type hamtNode struct {
+	bitmap  uint32
+	entries []hamtEntry
+}
+
+type hamtEntry struct {
+	hash  uint32
+	key   string
+	pair  MapPair
+	child *hamtNode
+}
+
+const (
+	hamtBits = 5
+	hamtMask = 1<<hamtBits - 1
+)
+
+var emptyHamt = &hamtNode{}
+
+func hashString(key string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return h.Sum32()
+}
+
+func (n *hamtNode) get(shift uint, hash uint32, key string) (MapPair, bool) {
+	for {
+		if shift >= 32 {
+			for _, e := range n.entries {
+				if e.key == key {
+					return e.pair, true
+				}
+			}
+			return MapPair{}, false
+		}
+		bit := uint32(1) << ((hash >> shift) & hamtMask)
+		if n.bitmap&bit == 0 {
+			return MapPair{}, false
+		}
+		e := n.entries[bits.OnesCount32(n.bitmap&(bit-1))]
+		if e.child == nil {
+			if e.key != key {
+				return MapPair{}, false
+			}
+			return e.pair, true
+		}
+		n = e.child
+		shift += hamtBits
+	}
+}
+
+// set returns a copy of n with key bound to pair, and whether the key was new.
+func (n *hamtNode) set(shift uint, hash uint32, key string, pair MapPair) (*hamtNode, bool) {
+	if shift >= 32 {
+		for i, e := range n.entries {
+			if e.key == key {
+				node := n.clone()
+				node.entries[i].pair = pair
+				return node, false
+			}
+		}
+		node := n.clone()
+		node.entries = append(node.entries, hamtEntry{hash: hash, key: key, pair: pair})
+		return node, true
+	}
+
+	bit := uint32(1) << ((hash >> shift) & hamtMask)
+	idx := bits.OnesCount32(n.bitmap & (bit - 1))
+
+	if n.bitmap&bit == 0 {
+		entries := make([]hamtEntry, len(n.entries)+1)
+		copy(entries, n.entries[:idx])
+		entries[idx] = hamtEntry{hash: hash, key: key, pair: pair}
+		copy(entries[idx+1:], n.entries[idx:])
+		return &hamtNode{bitmap: n.bitmap | bit, entries: entries}, true
+	}
+
+	e := n.entries[idx]
+	node := n.clone()
+	switch {
+	case e.child != nil:
+		child, added := e.child.set(shift+hamtBits, hash, key, pair)
+		node.entries[idx].child = child
+		return node, added
+	case e.key == key:
+		node.entries[idx].pair = pair
+		return node, false
+	default:
+		child, _ := emptyHamt.set(shift+hamtBits, e.hash, e.key, e.pair)
+		child, _ = child.set(shift+hamtBits, hash, key, pair)
+		node.entries[idx] = hamtEntry{child: child}
+		return node, true
+	}
+}
+
+// remove returns a copy of n without key, and whether the key was present.
+func (n *hamtNode) remove(shift uint, hash uint32, key string) (*hamtNode, bool) {
+	if shift >= 32 {
+		for i, e := range n.entries {
+			if e.key == key {
+				return &hamtNode{entries: deleteEntry(n.entries, i)}, true
+			}
+		}
+		return n, false
+	}
+
+	bit := uint32(1) << ((hash >> shift) & hamtMask)
+	if n.bitmap&bit == 0 {
+		return n, false
+	}
+	idx := bits.OnesCount32(n.bitmap & (bit - 1))
+	e := n.entries[idx]
+
+	if e.child != nil {
+		child, removed := e.child.remove(shift+hamtBits, hash, key)
+		if !removed {
+			return n, false
+		}
+		if len(child.entries) > 0 {
+			node := n.clone()
+			node.entries[idx].child = child
+			return node, true
+		}
+	} else if e.key != key {
+		return n, false
+	}
+	return &hamtNode{bitmap: n.bitmap &^ bit, entries: deleteEntry(n.entries, idx)}, true
+}
+
+func (n *hamtNode) clone() *hamtNode {
+	entries := make([]hamtEntry, len(n.entries))
+	copy(entries, n.entries)
+	return &hamtNode{bitmap: n.bitmap, entries: entries}
+}
+
+func (n *hamtNode) each(yield func(string, MapPair) bool) bool {
+	for _, e := range n.entries {
+		if e.child != nil {
+			if !e.child.each(yield) {
+				return false
+			}
+		} else if !yield(e.key, e.pair) {
+			return false
+		}
+	}
+	return true
+}
+
+func deleteEntry(entries []hamtEntry, i int) []hamtEntry {
+	result := make([]hamtEntry, 0, len(entries)-1)
+	result = append(result, entries[:i]...)
+	return append(result, entries[i+1:]...)
+}
+
+// NewMap creates an empty map.
+func NewMap() *MapObject {
+	return &MapObject{root: emptyHamt}
+}
+
+// Len returns the number of pairs in the map.
+func (m *MapObject) Len() int {
+	return m.count
+}
+
+// Get looks up a pair by the HashKey of its key.
+func (m *MapObject) Get(hashKey string) (MapPair, bool) {
+	return m.root.get(0, hashString(hashKey), hashKey)
+}
+
+// Set returns a new map with the pair stored under hashKey.
+func (m *MapObject) Set(hashKey string, pair MapPair) *MapObject {
+	root, added := m.root.set(0, hashString(hashKey), hashKey, pair)
+	count := m.count
+	if added {
+		count++
+	}
+	return &MapObject{root: root, count: count}
+}
+
+// Delete returns a new map without the pair stored under hashKey.
+func (m *MapObject) Delete(hashKey string) *MapObject {
+	root, removed := m.root.remove(0, hashString(hashKey), hashKey)
+	if !removed {
+		return m
+	}
+	return &MapObject{root: root, count: m.count - 1}
+}
+
+// All iterates over the map's hash keys and pairs in unspecified order.
+func (m *MapObject) All() iter.Seq2[string, MapPair] {
+	return func(yield func(string, MapPair) bool) {
+		m.root.each(yield)
+	}
+}
@@ -0,0 +1,134 @@
+package object
+
+import (
+	"fmt"
+	"testing"
+)
+
+func pair(i int) MapPair {
+	return MapPair{Key: &IntegerObject{Value: int64(i)}, Value: &IntegerObject{Value: int64(i * 10)}}
+}
+
+func checkMap(t *testing.T, m *MapObject, want map[string]int) {
+	t.Helper()
+	if m.Len() != len(want) {
+		t.Fatalf("Len() = %d, want %d", m.Len(), len(want))
+	}
+	for key, i := range want {
+		p, ok := m.Get(key)
+		if !ok || p.Value.(*IntegerObject).Value != int64(i*10) {
+			t.Fatalf("Get(%q) = %v, %v", key, p.Value, ok)
+		}
+	}
+	seen := 0
+	for key := range m.All() {
+		if _, ok := want[key]; !ok {
+			t.Fatalf("All() yielded %q, which is not in the map", key)
+		}
+		seen++
+	}
+	if seen != len(want) {
+		t.Fatalf("All() yielded %d pairs, want %d", seen, len(want))
+	}
+}
+
+func TestMapSetGetDelete(t *testing.T) {
+	m := NewMap()
+	want := map[string]int{}
+	for i := range 5000 {
+		key := fmt.Sprint(i)
+		m = m.Set(key, pair(i))
+		want[key] = i
+	}
+	checkMap(t, m, want)
+
+	full := m
+	for i := 0; i < 5000; i += 2 {
+		key := fmt.Sprint(i)
+		m = m.Delete(key)
+		delete(want, key)
+	}
+	checkMap(t, m, want)
+	if full.Len() != 5000 {
+		t.Fatalf("deleting changed the older map: Len() = %d", full.Len())
+	}
+	if _, ok := m.Get("0"); ok {
+		t.Fatalf("deleted key is still present")
+	}
+	if m.Delete("missing") != m {
+		t.Fatalf("deleting a missing key made a new map")
+	}
+
+	m = m.Set("1", pair(2))
+	want["1"] = 2
+	checkMap(t, m, want)
+}
+
+// TestMapCollisions stores keys whose hashes are equal, which end up in the flat
+// list below the last level of the trie.
+func TestMapCollisions(t *testing.T) {
+	const hash = 0x12345678
+	root := emptyHamt
+	for i, key := range []string{"a", "b", "c"} {
+		var added bool
+		root, added = root.set(0, hash, key, pair(i))
+		if !added {
+			t.Fatalf("set(%q) did not add a pair", key)
+		}
+	}
+	if _, added := root.set(0, hash, "b", pair(9)); added {
+		t.Fatalf("replacing a colliding key added a pair")
+	}
+	for i, key := range []string{"a", "b", "c"} {
+		if p, ok := root.get(0, hash, key); !ok || p.Value.(*IntegerObject).Value != int64(i*10) {
+			t.Fatalf("get(%q) = %v, %v", key, p.Value, ok)
+		}
+	}
+	if _, ok := root.get(0, hash, "d"); ok {
+		t.Fatalf("get found a key that was never set")
+	}
+
+	without, removed := root.remove(0, hash, "b")
+	if !removed {
+		t.Fatalf("remove(b) did not remove it")
+	}
+	if _, ok := without.get(0, hash, "b"); ok {
+		t.Fatalf("b is still present after remove")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := without.get(0, hash, key); !ok {
+			t.Fatalf("remove(b) also removed %q", key)
+		}
+	}
+	if _, ok := root.get(0, hash, "b"); !ok {
+		t.Fatalf("remove changed the older trie")
+	}
+	if _, removed := without.remove(0, hash, "d"); removed {
+		t.Fatalf("removed a key that was never set")
+	}
+
+	for _, key := range []string{"a", "c"} {
+		without, _ = without.remove(0, hash, key)
+	}
+	if len(without.entries) != 0 {
+		t.Fatalf("trie is not empty after removing every key: %d entries", len(without.entries))
+	}
+}
+
+func BenchmarkMapSet(b *testing.B) {
+	for _, n := range []int{1000, 10000, 100000} {
+		keys := make([]string, n)
+		for i := range keys {
+			keys[i] = fmt.Sprint(i)
+		}
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			p := pair(1)
+			for b.Loop() {
+				m := NewMap()
+				for _, key := range keys {
+					m = m.Set(key, p)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package object
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FS is the file system of the I/O builtins: an fs.FS that can also write files.
+// Unlike os.DirFS, implementations may accept any path the host allows, including
+// absolute ones.
+type FS interface {
+	fs.FS
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+}
+
+// IO is what the I/O builtins read from and write to. A nil field is not available
+// to scripts: readln fails without Stdin, print without Stdout, and so on. The
+// methods are safe for concurrent use by the stages of a parallel pipeline.
+type IO struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	FS     FS
+	// Sandbox, if set, limits what scripts may do with the fields above and with
+	// capabilities such as the network that no field stands for.
+	Sandbox *Sandbox
+
+	mu     sync.Mutex
+	reader *bufio.Reader // buffers Stdin across readln calls
+}
+
+// StdIO returns an IO on the process's standard streams and file system.
+func StdIO() *IO {
+	return &IO{Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr, FS: OSFS{}}
+}
+
+// ErrUnavailable is returned when a script uses a stream or file system its IO lacks.
+var ErrUnavailable = errors.New("not available")
+
+// ErrDenied is wrapped by the errors returned for what the Sandbox does not allow.
+// Builtins report them as a FAIL with Code PERMISSION_DENIED.
+var ErrDenied = errors.New("permission denied")
+
+// Sandbox is a set of permissions for untrusted scripts. Its zero value denies
+// nothing.
+type Sandbox struct {
+	// ReadRoots, if not nil, are the only directories below which files may be
+	// read. An empty, non-nil list allows no reads.
+	ReadRoots []string
+	// WriteRoots, if not nil, are the only directories below which files may be
+	// written.
+	WriteRoots []string
+	NoStdin    bool
+	NoNetwork  bool
+	NoExec     bool
+}
+
+// Capability is an effect that a Sandbox can deny as a whole.
+type Capability string
+
+const (
+	StdinAccess   Capability = "stdin"
+	NetworkAccess Capability = "network"
+	ExecAccess    Capability = "exec"
+)
+
+// Check returns an error wrapping ErrDenied if the sandbox denies c. Builtins
+// that reach the network or run programs must call it first.
+func (s *IO) Check(c Capability) error {
+	sb := s.Sandbox
+	if sb == nil {
+		return nil
+	}
+	denied := map[Capability]bool{StdinAccess: sb.NoStdin, NetworkAccess: sb.NoNetwork, ExecAccess: sb.NoExec}
+	if denied[c] {
+		return fmt.Errorf("%s: %w", c, ErrDenied)
+	}
+	return nil
+}
+
+// Open opens a file for reading, if the sandbox allows it. It makes an IO an
+// fs.FS, so that hosts can read imported modules with the same permissions as
+// the read builtins.
+func (s *IO) Open(name string) (fs.File, error) {
+	if err := s.checkPath("read", name); err != nil {
+		return nil, err
+	}
+	return s.FS.Open(name)
+}
+
+// ReadFile reads a whole file, if the sandbox allows it.
+func (s *IO) ReadFile(name string) ([]byte, error) {
+	if err := s.checkPath("read", name); err != nil {
+		return nil, err
+	}
+	return fs.ReadFile(s.FS, name)
+}
+
+// WriteFile writes a whole file, if the sandbox allows it.
+func (s *IO) WriteFile(name string, data []byte) error {
+	if err := s.checkPath("write", name); err != nil {
+		return err
+	}
+	return s.FS.WriteFile(name, data, 0644)
+}
+
+func (s *IO) checkPath(op, name string) error {
+	if s.FS == nil {
+		return ErrUnavailable
+	}
+	if s.Sandbox == nil {
+		return nil
+	}
+	roots := s.Sandbox.ReadRoots
+	if op == "write" {
+		roots = s.Sandbox.WriteRoots
+	}
+	if roots == nil {
+		return nil
+	}
+	target := realPath(name)
+	for _, root := range roots {
+		rel, err := filepath.Rel(realPath(root), target)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s %s: %w", op, name, ErrDenied)
+}
+
+// realPath makes name absolute and resolves the symbolic links in the part of it
+// that exists, so that a link cannot lead out of a sandbox root.
+func realPath(name string) string {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return filepath.Clean(name)
+	}
+	rest := ""
+	for dir := abs; ; dir = filepath.Dir(dir) {
+		if real, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(real, rest)
+		}
+		if dir == filepath.Dir(dir) {
+			return abs
+		}
+		rest = filepath.Join(filepath.Base(dir), rest)
+	}
+}
+
+// ReadLine reads the next line from Stdin without its line ending. Input read
+// ahead is kept for the next call.
+func (s *IO) ReadLine() (string, error) {
+	if err := s.Check(StdinAccess); err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Stdin == nil {
+		return "", ErrUnavailable
+	}
+	if s.reader == nil {
+		s.reader = bufio.NewReader(s.Stdin)
+	}
+	line, err := s.reader.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	return string(bytes.TrimRight([]byte(line), "\r\n")), err
+}
+
+// WriteLine writes text and a newline to Stdout, or to Stderr when stderr is true.
+func (s *IO) WriteLine(stderr bool, text string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w := s.Stdout
+	if stderr {
+		w = s.Stderr
+	}
+	if w == nil {
+		return ErrUnavailable
+	}
+	_, err := io.WriteString(w, text+"\n")
+	return err
+}
+
+// OSFS is the file system of the process. Paths are used as given, relative to
+// the working directory.
+type OSFS struct{}
+
+func (OSFS) Open(name string) (fs.File, error) { return os.Open(name) }
+
+func (OSFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return os.WriteFile(name, data, perm)
+}
+
+// MemFS is an in-memory file system, e.g. for tests and for hosts that must not
+// let scripts touch real files. Paths are cleaned, so "a/../b" and "./b" name "b".
+type MemFS struct {
+	mu    sync.RWMutex
+	files map[string][]byte
+}
+
+// NewMemFS creates a MemFS holding files, keyed by path.
+func NewMemFS(files map[string]string) *MemFS {
+	m := &MemFS{files: map[string][]byte{}}
+	for name, content := range files {
+		m.files[path.Clean(name)] = []byte(content)
+	}
+	return m
+}
+
+func (m *MemFS) Open(name string) (fs.File, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, ok := m.files[path.Clean(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &memFile{Reader: bytes.NewReader(data), name: path.Base(name), size: int64(len(data))}, nil
+}
+
+func (m *MemFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[path.Clean(name)] = bytes.Clone(data)
+	return nil
+}
+
+// ReadFile returns the content of a file written by a script.
+func (m *MemFS) ReadFile(name string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, ok := m.files[path.Clean(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	return bytes.Clone(data), nil
+}
+
+type memFile struct {
+	*bytes.Reader
+	name string
+	size int64
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return memFileInfo{f}, nil }
+func (f *memFile) Close() error               { return nil }
+
+type memFileInfo struct{ f *memFile }
+
+func (i memFileInfo) Name() string       { return i.f.name }
+func (i memFileInfo) Size() int64        { return i.f.size }
+func (i memFileInfo) Mode() fs.FileMode  { return 0644 }
+func (i memFileInfo) ModTime() time.Time { return time.Time{} }
+func (i memFileInfo) IsDir() bool        { return false }
+func (i memFileInfo) Sys() any           { return nil }
@@ -0,0 +1,407 @@
+// Package object는 Duet 값(MemoryObject)과 변수를 저장하는 Memory를 정의합니다.
+// FromGo와 ToGo는 Go 값과 Duet 값을 서로 변환합니다.
+package object
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"duet/ast"
+	"duet/token"
+)
+
+// MemoryObjectType은 메모리에 저장될 객체의 타입을 나타냅니다.
+type MemoryObjectType string
+
+const (
+	INTEGER_OBJ      = "INTEGER"
+	FLOAT_OBJ        = "FLOAT"
+	STRING_OBJ       = "STRING"
+	BOOLEAN_OBJ      = "BOOLEAN"
+	NIL_OBJ          = "NIL"
+	RETURN_VALUE_OBJ = "RETURN_VALUE"
+	ERROR_OBJ        = "ERROR"
+	FAIL_OBJ         = "FAIL"
+	FUNCTION_OBJ     = "FUNCTION"
+	LIST_OBJ         = "LIST"
+	BUILTIN_OBJ      = "BUILTIN"
+	MAP_OBJ          = "MAP"
+	TAIL_CALL_OBJ    = "TAIL_CALL"
+	STREAM_OBJ       = "STREAM"
+	CHAN_OBJ         = "CHAN"
+	MODULE_OBJ       = "MODULE"
+)
+
+// MemoryObject는 인터프리터에서 다루는 모든 값(객체)이 구현해야 하는 인터페이스입니다.
+type MemoryObject interface {
+	Type() MemoryObjectType
+	Inspect() string
+}
+
+// 각 데이터 타입을 위한 구조체 정의
+
+type IntegerObject struct {
+	Value int64
+}
+
+func (i *IntegerObject) Type() MemoryObjectType { return INTEGER_OBJ }
+func (i *IntegerObject) Inspect() string        { return fmt.Sprintf("%d", i.Value) }
+
+type FloatObject struct {
+	Value float64
+}
+
+func (f *FloatObject) Type() MemoryObjectType { return FLOAT_OBJ }
+func (f *FloatObject) Inspect() string        { return fmt.Sprintf("%f", f.Value) }
+
+type StringObject struct {
+	Value string
+}
+
+func (s *StringObject) Type() MemoryObjectType { return STRING_OBJ }
+func (s *StringObject) Inspect() string        { return s.Value }
+
+type BooleanObject struct {
+	Value bool
+}
+
+func (b *BooleanObject) Type() MemoryObjectType { return BOOLEAN_OBJ }
+func (b *BooleanObject) Inspect() string        { return fmt.Sprintf("%t", b.Value) }
+
+type NilObject struct{}
+
+func (n *NilObject) Type() MemoryObjectType { return NIL_OBJ }
+func (n *NilObject) Inspect() string        { return "nil" }
+
+type ReturnValueObject struct {
+	Value MemoryObject
+}
+
+func (rv *ReturnValueObject) Type() MemoryObjectType { return RETURN_VALUE_OBJ }
+func (rv *ReturnValueObject) Inspect() string        { return rv.Value.Inspect() }
+
+// BUDGET_EXCEEDED는 실행 예산(단계 수, 시간, 할당 크기)을 초과했을 때의 에러 코드입니다.
+const BUDGET_EXCEEDED = "BUDGET_EXCEEDED"
+
+type ErrorObject struct {
+	Message string
+	Code    string // 에러 종류를 구분하는 코드. 일반 에러는 비어 있습니다.
+}
+
+func (e *ErrorObject) Type() MemoryObjectType { return ERROR_OBJ }
+func (e *ErrorObject) Inspect() string        { return "ERROR: " + e.Message }
+
+// Error는 ErrorObject를 Go의 error로 쓸 수 있게 합니다. 호스트 API는 실행 에러를 이 값으로 반환합니다.
+func (e *ErrorObject) Error() string { return e.Message }
+
+// PERMISSION_DENIED는 샌드박스가 허용하지 않은 입출력을 시도했을 때의 실패 코드입니다.
+const PERMISSION_DENIED = "PERMISSION_DENIED"
+
+type FailObject struct {
+	Message string
+	Code    string // 실패 종류를 구분하는 코드. 일반 실패는 비어 있습니다.
+}
+
+func (e *FailObject) Type() MemoryObjectType { return FAIL_OBJ }
+func (e *FailObject) Inspect() string        { return e.Message }
+
+func NewFail(format string, a ...interface{}) *FailObject {
+	return &FailObject{Message: fmt.Sprintf(format, a...)}
+}
+
+type FunctionObject struct {
+	Name       *ast.Identifier
+	Token      token.Token // The function type token (e.g., PROC, CONS, SUPP)
+	Parameters []*ast.Parameter
+	ReturnType *ast.Identifier
+	Body       ast.Expression
+	Mem        *Memory
+	Generator  bool // 본문에 yield가 있는 supp이면 참
+
+	// 아래 필드는 엔진이 채우고 엔진만 사용합니다.
+	Code any // VM 백엔드가 사용하는 바이트코드 (처음 호출될 때 컴파일)
+	Memo any // @memo proc의 결과 캐시 (메모이제이션하지 않으면 nil)
+}
+
+// ModuleObject는 import 문이 이름에 바인딩한 모듈입니다.
+// Globals는 모듈의 문들이 정의한 전역 값이며, 밖에서는 이름이 _로 시작하지 않는 함수만 쓸 수 있습니다.
+type ModuleObject struct {
+	Path    string
+	Globals *Memory
+	Module  any // 엔진이 검사한 모듈 (엔진만 사용)
+}
+
+func (m *ModuleObject) Type() MemoryObjectType { return MODULE_OBJ }
+func (m *ModuleObject) Inspect() string        { return fmt.Sprintf("module %q", m.Path) }
+
+func (f *FunctionObject) Type() MemoryObjectType { return FUNCTION_OBJ }
+func (f *FunctionObject) Inspect() string {
+	var out bytes.Buffer
+	params := []string{}
+	for _, p := range f.Parameters {
+		params = append(params, p.Name.String()+":"+p.Type.String())
+	}
+
+	out.WriteString(f.Token.Literal)
+	out.WriteString(" ")
+	out.WriteString(f.Name.String())
+	if len(f.Parameters) > 0 {
+		out.WriteString("(")
+		out.WriteString(strings.Join(params, ", "))
+		out.WriteString(")")
+	}
+	if f.ReturnType != nil {
+		out.WriteString(":")
+		out.WriteString(f.ReturnType.String())
+	}
+	out.WriteString(" -> ")
+	out.WriteString(f.Body.String())
+
+	return out.String()
+}
+
+// ListObject는 영속(persistent) 벡터 위의 [start, end) 구간입니다.
+// push와 rest가 기존 노드를 공유하므로 원본 리스트는 변경되지 않습니다.
+type ListObject struct {
+	vec   *vector
+	start int
+	end   int
+}
+
+func (l *ListObject) Type() MemoryObjectType { return LIST_OBJ }
+func (l *ListObject) Inspect() string {
+	var out bytes.Buffer
+	elements := []string{}
+	for _, e := range l.All() {
+		elements = append(elements, e.Inspect())
+	}
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+	return out.String()
+}
+
+type MapPair struct {
+	Key   MemoryObject
+	Value MemoryObject
+}
+
+// MapObject는 키의 HashKey를 기준으로 하는 영속 해시 트라이(HAMT)입니다.
+type MapObject struct {
+	root  *hamtNode
+	count int
+}
+
+func (m *MapObject) Type() MemoryObjectType { return MAP_OBJ }
+func (m *MapObject) Inspect() string {
+	var out bytes.Buffer
+	pairs := []string{}
+	for _, pair := range m.SortedPairs() {
+		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
+	}
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+	return out.String()
+}
+
+// SortedPairs는 키 순서로 정렬된 키-값 쌍 목록을 반환합니다.
+// Go 맵의 순회 순서는 무작위이므로, 출력과 순회가 항상 같은 결과를 내도록 사용합니다.
+func (m *MapObject) SortedPairs() []MapPair {
+	pairs := make([]MapPair, 0, m.count)
+	for _, pair := range m.All() {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return CompareObjects(pairs[i].Key, pairs[j].Key) < 0
+	})
+	return pairs
+}
+
+// getFloat는 숫자 값을 float64로 반환합니다. 숫자가 아니면 false를 반환합니다.
+func GetFloat(obj MemoryObject) (float64, bool) {
+	switch obj := obj.(type) {
+	case *IntegerObject:
+		return float64(obj.Value), true
+	case *FloatObject:
+		return obj.Value, true
+	default:
+		return 0, false
+	}
+}
+
+// compareObjects는 두 값의 순서를 비교합니다.
+// 숫자끼리는 값으로, 문자열끼리는 사전 순으로 비교하며,
+// 서로 다른 타입은 타입 이름 순으로 정렬합니다.
+func CompareObjects(a, b MemoryObject) int {
+	af, aNum := GetFloat(a)
+	bf, bNum := GetFloat(b)
+	if aNum && bNum {
+		switch {
+		case af < bf:
+			return -1
+		case af > bf:
+			return 1
+		default:
+			return 0
+		}
+	}
+	if a.Type() != b.Type() {
+		return strings.Compare(string(a.Type()), string(b.Type()))
+	}
+	switch a := a.(type) {
+	case *StringObject:
+		return strings.Compare(a.Value, b.(*StringObject).Value)
+	case *BooleanObject:
+		if a.Value == b.(*BooleanObject).Value {
+			return 0
+		}
+		if !a.Value {
+			return -1
+		}
+		return 1
+	default:
+		return strings.Compare(a.Inspect(), b.Inspect())
+	}
+}
+
+type Hashable interface {
+	HashKey() string
+}
+
+func (i *IntegerObject) HashKey() string { return fmt.Sprintf("i:%d", i.Value) }
+func (s *StringObject) HashKey() string  { return fmt.Sprintf("s:%s", s.Value) }
+func (b *BooleanObject) HashKey() string { return fmt.Sprintf("b:%t", b.Value) }
+
+type BuiltinFunction func(args ...MemoryObject) MemoryObject
+
+// Caller는 빌트인 함수가 Duet 함수(FunctionObject 또는 BuiltinObject)를 다시 호출할 때 사용하는 콜백입니다.
+type Caller func(fn MemoryObject, args ...MemoryObject) MemoryObject
+
+// HigherOrderFunction은 엔진으로부터 Caller를 전달받는 빌트인 함수입니다.
+type HigherOrderFunction func(call Caller, args ...MemoryObject) MemoryObject
+
+// Spawner는 다른 고루틴에서 동시에 쓸 수 있는 Caller n개를 만듭니다.
+// 반환된 stop을 호출하면 그 Caller들로 실행 중인 함수가 곧 에러로 중단됩니다.
+type Spawner func(n int) (callers []Caller, stop func())
+
+// ParallelFunction은 인자로 받은 함수를 여러 고루틴에서 동시에 호출하는 빌트인 함수입니다.
+type ParallelFunction func(spawn Spawner, args ...MemoryObject) MemoryObject
+
+// Scheduler는 spawn한 고루틴과 채널을 관리하는, 실행 중인 프로그램의 스케줄러입니다.
+// 엔진이 구현합니다.
+type Scheduler interface {
+	// NewChan은 이 스케줄러에 속한, 버퍼 크기가 size인 채널을 만듭니다.
+	NewChan(size int) MemoryObject
+}
+
+// BlockingFunction은 채널처럼 다른 고루틴을 기다릴 수 있는 빌트인 함수입니다.
+// 실행 중인 프로그램의 스케줄러를 전달받습니다.
+type BlockingFunction func(s Scheduler, args ...MemoryObject) MemoryObject
+
+// SizeFunction은 빌트인이 인자로부터 만들 리스트의 길이나 문자열의 바이트 수를 호출 전에 계산합니다.
+// 인자가 잘못되었으면 0을 반환하여 빌트인이 직접 에러를 내게 합니다.
+type SizeFunction func(args ...MemoryObject) int
+
+// IOFunction은 엔진에 설정된 입출력(IO)을 사용하는 빌트인 함수입니다.
+type IOFunction func(sys *IO, args ...MemoryObject) MemoryObject
+
+// BuiltinObject는 Fn, HigherOrder, Parallel, Blocking, IO 중 하나를 가집니다.
+// 인자로 받은 함수를 호출해야 하는 빌트인(map, filter 등)은 HigherOrder를,
+// 그 함수를 병렬로 호출하는 빌트인(pmap)은 Parallel을, 채널 빌트인은 Blocking을,
+// 표준 입출력이나 파일을 쓰는 빌트인은 IO를 사용합니다.
+type BuiltinObject struct {
+	Fn          BuiltinFunction
+	HigherOrder HigherOrderFunction
+	Parallel    ParallelFunction
+	Blocking    BlockingFunction
+	IO          IOFunction
+	// Effectful는 입출력처럼 외부 세계와 상호작용하는 빌트인을 표시합니다.
+	// proc과 @memo proc에서는 호출할 수 없습니다.
+	Effectful bool
+	// Streams가 거짓인 빌트인은 스트림 인자를 받으면 리스트로 모두 읽은 뒤에 호출됩니다.
+	Streams bool
+	// AcceptsFail이 참인 빌트인은 FAIL 인자를 그대로 반환하지 않고 받아서 처리합니다.
+	AcceptsFail bool
+	// Size가 있으면 엔진은 빌트인을 호출하기 전에 결과의 크기를 MaxAllocSize와 비교하여,
+	// 인자보다 훨씬 큰 결과를 만드는 빌트인(range, join, replace)이 할당부터 하지 않게 합니다.
+	Size SizeFunction
+}
+
+func (b *BuiltinObject) Type() MemoryObjectType { return BUILTIN_OBJ }
+func (b *BuiltinObject) Inspect() string        { return "builtin function" }
+
+var (
+	True  = &BooleanObject{Value: true}
+	False = &BooleanObject{Value: false}
+	Nil   = &NilObject{}
+)
+
+// Memory는 변수와 함수를 저장하는 환경(Environment)입니다.
+// outer 필드를 통해 중첩된 스코프(lexical scope)를 구현합니다.
+// 전역 스코프는 이름으로 값을 찾고, 함수 호출과 for 순회가 만드는 지역 스코프는
+// Resolver가 정한 슬롯 번호로 값을 찾습니다.
+// 병렬 파이프라인의 단계들이 동시에 읽을 수 있도록 이름 저장소는 잠금으로 보호됩니다.
+type Memory struct {
+	mu    sync.RWMutex
+	store map[string]MemoryObject
+	slots []MemoryObject
+	outer *Memory
+}
+
+// NewMemory는 새로운 최상위 메모리(전역 스코프)를 생성합니다.
+func NewMemory() *Memory {
+	s := make(map[string]MemoryObject)
+	return &Memory{store: s, outer: nil}
+}
+
+// NewEnclosedMemory는 외부 스코프를 감싸는 새로운 내부 스코프를 생성합니다.
+// 함수 호출 시 지역 변수를 관리하기 위해 사용됩니다.
+func NewEnclosedMemory(outer *Memory) *Memory {
+	mem := NewMemory()
+	mem.outer = outer
+	return mem
+}
+
+// NewLocalMemory는 slots를 지역 변수로 가지는 내부 스코프를 생성합니다.
+func NewLocalMemory(outer *Memory, slots []MemoryObject) *Memory {
+	return &Memory{slots: slots, outer: outer}
+}
+
+// Get은 현재 스코프 또는 외부 스코프에서 변수 값을 찾습니다.
+func (m *Memory) Get(name string) (MemoryObject, bool) {
+	for ; m != nil; m = m.outer {
+		if m.store == nil {
+			continue // 슬롯만 가진 지역 스코프
+		}
+		m.mu.RLock()
+		obj, ok := m.store[name]
+		m.mu.RUnlock()
+		if ok {
+			return obj, true
+		}
+	}
+	return nil, false
+}
+
+// Lookup은 depth단계 바깥 스코프의 slot번째 지역 변수를 반환합니다.
+func (m *Memory) Lookup(depth, slot int) MemoryObject {
+	for ; depth > 0; depth-- {
+		m = m.outer
+	}
+	return m.slots[slot]
+}
+
+// Set은 현재 스코프에 변수 값을 설정(또는 생성)합니다.
+func (m *Memory) Set(name string, val MemoryObject) MemoryObject {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.store == nil {
+		m.store = make(map[string]MemoryObject)
+	}
+	m.store[name] = val
+	return val
+}
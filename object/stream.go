@@ -0,0 +1,84 @@
+package object
+
+import (
+	"iter"
+)
+
+// StreamObject is a lazy, single-pass sequence of values. Elements are produced
+// only when a consumer asks for the next one, so a pipeline over a stream runs in
+// constant memory. An ERROR element ends the stream and is passed on to the consumer.
+type StreamObject struct {
+	next  func() (MemoryObject, bool)
+	close func()
+	done  bool
+}
+
+func (s *StreamObject) Type() MemoryObjectType { return STREAM_OBJ }
+func (s *StreamObject) Inspect() string        { return "stream" }
+
+// NewStream creates a stream from a pull function. close, which may be nil, releases
+// the resources of the producer when the consumer stops early.
+func NewStream(next func() (MemoryObject, bool), close func()) *StreamObject {
+	return &StreamObject{next: next, close: close}
+}
+
+// Next returns the next element, or false once the stream is exhausted.
+func (s *StreamObject) Next() (MemoryObject, bool) {
+	if s.done {
+		return nil, false
+	}
+	obj, ok := s.next()
+	if !ok || obj.Type() == ERROR_OBJ {
+		s.Close()
+	}
+	return obj, ok
+}
+
+// Close stops the stream; later calls to Next return false.
+func (s *StreamObject) Close() {
+	if s.done {
+		return
+	}
+	s.done = true
+	if s.close != nil {
+		s.close()
+	}
+}
+
+// All iterates over the remaining elements of the stream.
+func (s *StreamObject) All() iter.Seq[MemoryObject] {
+	return func(yield func(MemoryObject) bool) {
+		for {
+			obj, ok := s.Next()
+			if !ok {
+				return
+			}
+			if !yield(obj) {
+				s.Close()
+				return
+			}
+		}
+	}
+}
+
+// Collect reads the remaining elements into a list, or returns the first error.
+func (s *StreamObject) Collect() MemoryObject {
+	obj, _ := s.CollectLimit(0)
+	return obj
+}
+
+// CollectLimit is Collect for streams that may hold at most max elements, or any
+// number if max <= 0. If there are more, it stops the stream and returns false.
+func (s *StreamObject) CollectLimit(max int) (MemoryObject, bool) {
+	var elements []MemoryObject
+	for obj := range s.All() {
+		if obj.Type() == ERROR_OBJ {
+			return obj, true
+		}
+		if max > 0 && len(elements) == max {
+			return nil, false
+		}
+		elements = append(elements, obj)
+	}
+	return NewList(elements), true
+}
@@ -0,0 +1,167 @@
+package object
+
+import (
+	"iter"
+)
+
+// vector is a persistent 32-way trie with a tail buffer, in the style of Clojure's
+// PersistentVector. Pushing copies at most one path of the trie plus the tail, so
+// older versions stay valid and share all untouched nodes with newer ones.
+type vector struct {
+	count int
+	shift uint
+	root  *vecNode
+	tail  []MemoryObject
+}
+
+type vecNode struct {
+	children []*vecNode
+	values   []MemoryObject
+}
+
+const (
+	vecBits  = 5
+	vecWidth = 1 << vecBits
+	vecMask  = vecWidth - 1
+)
+
+var emptyVector = &vector{shift: vecBits, root: &vecNode{}}
+
+// vectorOf builds a vector from elements, taking ownership of the slice.
+func vectorOf(elements []MemoryObject) *vector {
+	v := emptyVector
+	for start := 0; start < len(elements); start += vecWidth {
+		end := min(start+vecWidth, len(elements))
+		v = v.pushChunk(elements[start:end:end])
+	}
+	return v
+}
+
+// tailOffset is the number of elements stored in the trie (always a multiple of vecWidth).
+func (v *vector) tailOffset() int {
+	return v.count - len(v.tail)
+}
+
+func (v *vector) nth(i int) MemoryObject {
+	if i >= v.tailOffset() {
+		return v.tail[i-v.tailOffset()]
+	}
+	node := v.root
+	for level := v.shift; level > 0; level -= vecBits {
+		node = node.children[(i>>level)&vecMask]
+	}
+	return node.values[i&vecMask]
+}
+
+func (v *vector) push(val MemoryObject) *vector {
+	if len(v.tail) < vecWidth {
+		tail := make([]MemoryObject, len(v.tail)+1, vecWidth)
+		copy(tail, v.tail)
+		tail[len(v.tail)] = val
+		return &vector{count: v.count + 1, shift: v.shift, root: v.root, tail: tail}
+	}
+	return v.pushChunk([]MemoryObject{val})
+}
+
+// pushChunk moves a full tail into the trie and starts a new tail with chunk.
+// It must only be called when the current tail is empty or full.
+func (v *vector) pushChunk(chunk []MemoryObject) *vector {
+	if len(v.tail) == 0 {
+		return &vector{count: v.count + len(chunk), shift: v.shift, root: v.root, tail: chunk}
+	}
+
+	leaf := &vecNode{values: v.tail}
+	treeCount := v.tailOffset()
+	root, shift := v.root, v.shift
+	if treeCount>>vecBits >= 1<<shift {
+		root = &vecNode{children: []*vecNode{v.root, newVecPath(shift, leaf)}}
+		shift += vecBits
+	} else {
+		root = pushVecTail(treeCount, shift, root, leaf)
+	}
+	return &vector{count: v.count + len(chunk), shift: shift, root: root, tail: chunk}
+}
+
+func pushVecTail(treeCount int, level uint, parent, leaf *vecNode) *vecNode {
+	subidx := (treeCount >> level) & vecMask
+	node := &vecNode{children: make([]*vecNode, len(parent.children), len(parent.children)+1)}
+	copy(node.children, parent.children)
+
+	var insert *vecNode
+	switch {
+	case level == vecBits:
+		insert = leaf
+	case subidx < len(parent.children):
+		insert = pushVecTail(treeCount, level-vecBits, parent.children[subidx], leaf)
+	default:
+		insert = newVecPath(level-vecBits, leaf)
+	}
+
+	if subidx < len(node.children) {
+		node.children[subidx] = insert
+	} else {
+		node.children = append(node.children, insert)
+	}
+	return node
+}
+
+func newVecPath(level uint, leaf *vecNode) *vecNode {
+	if level == 0 {
+		return leaf
+	}
+	return &vecNode{children: []*vecNode{newVecPath(level-vecBits, leaf)}}
+}
+
+// NewList creates a list from elements, taking ownership of the slice.
+func NewList(elements []MemoryObject) *ListObject {
+	v := vectorOf(elements)
+	return &ListObject{vec: v, end: v.count}
+}
+
+// Len returns the number of elements in the list.
+func (l *ListObject) Len() int {
+	return l.end - l.start
+}
+
+// At returns the i-th element of the list. i must be within bounds.
+func (l *ListObject) At(i int) MemoryObject {
+	return l.vec.nth(l.start + i)
+}
+
+// All iterates over the list's indexes and elements in order.
+func (l *ListObject) All() iter.Seq2[int, MemoryObject] {
+	return func(yield func(int, MemoryObject) bool) {
+		for i := l.start; i < l.end; i++ {
+			if !yield(i-l.start, l.vec.nth(i)) {
+				return
+			}
+		}
+	}
+}
+
+// Elements copies the list into a new Go slice.
+func (l *ListObject) Elements() []MemoryObject {
+	elements := make([]MemoryObject, 0, l.Len())
+	for _, el := range l.All() {
+		elements = append(elements, el)
+	}
+	return elements
+}
+
+// Push returns a new list with el appended. When the list is a suffix view of its
+// vector (the usual case after Rest or Drop), this shares all existing nodes.
+// Other views, such as those left by Take, end before the vector does, so their
+// elements are copied into a new vector: one push costs O(Len()), and pushes onto
+// the result share nodes again.
+func (l *ListObject) Push(el MemoryObject) *ListObject {
+	if l.end != l.vec.count {
+		return NewList(append(l.Elements(), el))
+	}
+	v := l.vec.push(el)
+	return &ListObject{vec: v, start: l.start, end: v.count}
+}
+
+// Slice returns the elements in [from, to) as a view sharing the same vector.
+func (l *ListObject) Slice(from, to int) *ListObject {
+	return &ListObject{vec: l.vec, start: l.start + from, end: l.start + to}
+}
@@ -0,0 +1,123 @@
+package object
+
+import (
+	"fmt"
+	"testing"
+)
+
+func ints(from, to int) []MemoryObject {
+	elements := make([]MemoryObject, 0, to-from)
+	for i := from; i < to; i++ {
+		elements = append(elements, &IntegerObject{Value: int64(i)})
+	}
+	return elements
+}
+
+// checkList fails unless l holds the integers from..to-1 in order.
+func checkList(t *testing.T, l *ListObject, from, to int) {
+	t.Helper()
+	if l.Len() != to-from {
+		t.Fatalf("Len() = %d, want %d", l.Len(), to-from)
+	}
+	for i := 0; i < l.Len(); i++ {
+		if got := l.At(i).(*IntegerObject).Value; got != int64(from+i) {
+			t.Fatalf("At(%d) = %d, want %d", i, got, from+i)
+		}
+	}
+	i := 0
+	for j, el := range l.All() {
+		if j != i || el.(*IntegerObject).Value != int64(from+i) {
+			t.Fatalf("All() yielded %d: %s at step %d", j, el.Inspect(), i)
+		}
+		i++
+	}
+}
+
+// sizes cross the boundaries of the tail (32), of one trie level (1024) and of
+// two levels (32768).
+var sizes = []int{0, 1, 31, 32, 33, 63, 64, 65, 1023, 1024, 1025, 1056, 1057, 32767, 32768, 32769, 33825}
+
+func TestNewList(t *testing.T) {
+	for _, n := range sizes {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			checkList(t, NewList(ints(0, n)), 0, n)
+		})
+	}
+}
+
+func TestPush(t *testing.T) {
+	l := NewList(nil)
+	for i := 0; i <= sizes[len(sizes)-1]; i++ {
+		for _, n := range sizes {
+			if i == n {
+				checkList(t, l, 0, n)
+			}
+		}
+		l = l.Push(&IntegerObject{Value: int64(i)})
+	}
+}
+
+func TestPushKeepsOlderVersions(t *testing.T) {
+	for _, n := range sizes {
+		base := NewList(ints(0, n))
+		a := base.Push(&IntegerObject{Value: int64(n)})
+		b := base.Push(&IntegerObject{Value: -1})
+		checkList(t, base, 0, n)
+		checkList(t, a, 0, n+1)
+		if got := b.At(n).(*IntegerObject).Value; got != -1 {
+			t.Fatalf("%d: pushing onto the same list twice: At(%d) = %d, want -1", n, n, got)
+		}
+	}
+}
+
+func TestSliceAndPush(t *testing.T) {
+	for _, n := range []int{33, 1025, 1057} {
+		l := NewList(ints(0, n))
+
+		// A suffix view, as left by rest or drop, shares the vector when pushed to.
+		suffix := l.Slice(1, n).Push(&IntegerObject{Value: int64(n)})
+		checkList(t, suffix, 1, n+1)
+
+		// A prefix view, as left by take, must not overwrite the elements after it.
+		prefix := l.Slice(0, n-5).Push(&IntegerObject{Value: int64(n - 5)})
+		checkList(t, prefix, 0, n-4)
+		checkList(t, prefix.Push(&IntegerObject{Value: int64(n - 4)}), 0, n-3)
+		checkList(t, l, 0, n)
+
+		middle := l.Slice(10, 20)
+		checkList(t, middle, 10, 20)
+		checkList(t, middle.Slice(2, 5), 12, 15)
+		checkList(t, middle.Push(&IntegerObject{Value: 20}), 10, 21)
+		checkList(t, l.Slice(n, n).Push(&IntegerObject{Value: 7}), 7, 8)
+	}
+}
+
+// The benchmarks build and walk lists of growing size; the time per element
+// should stay flat as n grows.
+
+func BenchmarkPush(b *testing.B) {
+	for _, n := range []int{1000, 10000, 100000} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			el := &IntegerObject{Value: 1}
+			for b.Loop() {
+				l := NewList(nil)
+				for range n {
+					l = l.Push(el)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkRest(b *testing.B) {
+	for _, n := range []int{1000, 10000, 100000} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			list := NewList(ints(0, n))
+			for b.Loop() {
+				for l := list; l.Len() > 0; l = l.Slice(1, l.Len()) {
+					_ = l.At(0)
+				}
+			}
+		})
+	}
+}
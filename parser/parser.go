@@ -0,0 +1,837 @@
+// Package parser builds the AST of a Duet program from its tokens.
+package parser
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"duet/ast"
+	"duet/lexer"
+	"duet/token"
+)
+
+// Precedence levels for operators
+const (
+	_ int = iota
+	LOWEST
+	PLINE       // |>
+	EQUALS      // ==
+	LESSGREATER // > or <
+	SUM         // +
+	PRODUCT     // *
+	PREFIX      // -X or !X
+	CALL        // myFunction(X)
+	INDEX       // array[index]
+)
+
+var precedences = map[token.TokenType]int{
+	token.EQ:                EQUALS,
+	token.NOT_EQ:            EQUALS,
+	token.LT:                LESSGREATER,
+	token.GT:                LESSGREATER,
+	token.LE:                LESSGREATER,
+	token.GE:                LESSGREATER,
+	token.PLUS:              SUM,
+	token.MINUS:             SUM,
+	token.SLASH:             PRODUCT,
+	token.ASTERISK:          PRODUCT,
+	token.MODULO:            PRODUCT,
+	token.PIPELINE:          PLINE,
+	token.PARALLEL_PIPELINE: PLINE,
+	token.LPAREN:            CALL,
+	token.LBRACKET:          INDEX,
+	token.DOT:               INDEX,
+}
+
+type (
+	prefixParseFn func() ast.Expression
+	infixParseFn  func(ast.Expression) ast.Expression
+)
+
+// Parser holds the lexer, tokens, and parsing functions.
+type Parser struct {
+	l      *lexer.Lexer
+	errors []string
+
+	curToken  token.Token
+	peekToken token.Token
+
+	prefixParseFns map[token.TokenType]prefixParseFn
+	infixParseFns  map[token.TokenType]infixParseFn
+}
+
+// New creates a new Parser.
+func NewParser(l *lexer.Lexer) *Parser {
+	p := &Parser{
+		l:      l,
+		errors: []string{},
+	}
+
+	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
+	p.registerPrefix(token.IDENT, p.parseIdentifier)
+	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
+	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.TRUE, p.parseBooleanLiteral)
+	p.registerPrefix(token.FALSE, p.parseBooleanLiteral)
+	p.registerPrefix(token.NIL, p.parseNilLiteral)
+	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
+	p.registerPrefix(token.LBRACKET, p.parseListLiteral)
+	p.registerPrefix(token.LBRACE, p.parseMapLiteral)
+	p.registerPrefix(token.IF, p.parseIfExpression)
+	p.registerPrefix(token.FOR, p.parseForExpression)
+	p.registerPrefix(token.BANG, p.parsePrefixExpression)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
+	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.FAIL, p.parseFailExpression)
+	p.registerPrefix(token.YIELD, p.parseYieldExpression)
+	p.registerPrefix(token.SPAWN, p.parseSpawnExpression)
+	p.registerPrefix(token.SELECT, p.parseSelectExpression)
+
+	p.infixParseFns = make(map[token.TokenType]infixParseFn)
+	p.registerInfix(token.PLUS, p.parseInfixExpression)
+	p.registerInfix(token.MINUS, p.parseInfixExpression)
+	p.registerInfix(token.SLASH, p.parseInfixExpression)
+	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
+	p.registerInfix(token.MODULO, p.parseInfixExpression)
+	p.registerInfix(token.EQ, p.parseInfixExpression)
+	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.LT, p.parseInfixExpression)
+	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.LE, p.parseInfixExpression)
+	p.registerInfix(token.GE, p.parseInfixExpression)
+	p.registerInfix(token.PIPELINE, p.parseInfixExpression)
+	p.registerInfix(token.PARALLEL_PIPELINE, p.parseInfixExpression)
+	p.registerInfix(token.LPAREN, p.parseCallExpression)
+	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.DOT, p.parseMemberExpression)
+
+	// Read two tokens, so curToken and peekToken are both set
+	p.nextToken()
+	p.nextToken()
+
+	return p
+}
+
+func (p *Parser) nextToken() {
+	p.curToken = p.peekToken
+	p.peekToken = p.l.NextToken()
+}
+
+// ParseProgram is the entry point for parsing.
+func (p *Parser) ParseProgram() *ast.Program {
+	program := &ast.Program{}
+	program.Statements = []ast.Statement{}
+
+	for p.curToken.Type != token.EOF {
+		stmt := p.parseStatement()
+		if stmt != nil {
+			program.Statements = append(program.Statements, stmt)
+		}
+		p.nextToken()
+	}
+
+	return program
+}
+
+func (p *Parser) parseStatement() ast.Statement {
+	switch p.curToken.Type {
+	case token.PROC, token.CONS, token.SUPP:
+		return p.parseFunctionStatement()
+	case token.AT:
+		return p.parseAnnotatedFunctionStatement()
+	case token.IMPORT:
+		return p.parseImportStatement()
+	default:
+		return p.parseExpressionStatement()
+	}
+}
+
+// parseAnnotatedFunctionStatement parses annotations followed by a function definition.
+// @memo proc fib(n:int):int -> ...
+// @memo(100) proc fib(n:int):int -> ...
+func (p *Parser) parseAnnotatedFunctionStatement() ast.Statement {
+	var annotations []*ast.Annotation
+	for p.curTokenIs(token.AT) {
+		annotation := &ast.Annotation{Token: p.curToken}
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		annotation.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+			annotation.Arguments = p.parseExpressionList(token.RPAREN)
+		}
+		annotations = append(annotations, annotation)
+		p.nextToken()
+	}
+
+	if !p.curTokenIs(token.PROC) && !p.curTokenIs(token.CONS) && !p.curTokenIs(token.SUPP) {
+		msg := fmt.Sprintf("annotations must be followed by a function definition, got %s", p.curToken.Type)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+	stmt := p.parseFunctionStatement()
+	if stmt == nil {
+		return nil
+	}
+	stmt.Annotations = annotations
+
+	for _, a := range annotations {
+		switch a.Name.Value {
+		case "memo":
+			if stmt.Token.Type != token.PROC {
+				p.errors = append(p.errors, fmt.Sprintf("@memo can only annotate a proc, got %s", stmt.Token.Literal))
+			}
+			if _, ok := ast.MemoSize(a); !ok {
+				p.errors = append(p.errors, "@memo takes at most one argument, a positive integer cache size")
+			}
+			// Reading a stream or a channel consumes it, so a cached result would hide that effect.
+			for _, param := range stmt.Parameters {
+				if typ := strings.TrimSuffix(param.Type.Value, "?"); typ == "stream" || typ == "chan" {
+					p.errors = append(p.errors, fmt.Sprintf("@memo proc %s cannot take a %s parameter: %s", stmt.Name.Value, typ, param.Name.Value))
+				}
+			}
+		default:
+			p.errors = append(p.errors, fmt.Sprintf("unknown annotation @%s", a.Name.Value))
+		}
+	}
+	return stmt
+}
+
+// parseImportStatement parses `import "path" as name`. Without `as`, the module is
+// named after the last element of its path, e.g. `text` for "lib/text.duet".
+func (p *Parser) parseImportStatement() ast.Statement {
+	stmt := &ast.ImportStatement{Token: p.curToken}
+	if !p.expectPeek(token.STRING) {
+		return nil
+	}
+	stmt.Path = p.curToken.Literal
+
+	if p.peekTokenIs(token.AS) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		return stmt
+	}
+
+	name := path.Base(stmt.Path)
+	name = strings.TrimSuffix(name, path.Ext(name))
+	if tok := lexer.New(name).NextToken(); tok.Type != token.IDENT || tok.Literal != name {
+		p.errors = append(p.errors, fmt.Sprintf("cannot name module %q after its path, use `as`", stmt.Path))
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: token.Token{Type: token.IDENT, Literal: name}, Value: name}
+	return stmt
+}
+
+func (p *Parser) parseFunctionStatement() *ast.FunctionStatement {
+	stmt := &ast.FunctionStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	// proc add(a:int, b:int):int -> a + b
+	// cons print_message(msg:str) -> print(msg)
+	// supp get_random_num:float -> random()
+	switch stmt.Token.Type {
+	case token.PROC:
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		stmt.Parameters = p.parseFunctionParameters()
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.ReturnType = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	case token.CONS:
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		stmt.Parameters = p.parseFunctionParameters()
+	case token.SUPP:
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.ReturnType = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	if !p.expectPeek(token.ARROW) {
+		return nil
+	}
+	p.nextToken()
+	stmt.Body = p.parseExpression(LOWEST)
+
+	return stmt
+}
+
+func (p *Parser) parseFunctionParameters() []*ast.Parameter {
+	params := []*ast.Parameter{}
+
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		return params
+	}
+
+	p.nextToken() // Consume LPAREN
+
+	// First parameter
+	if p.curToken.Type != token.IDENT {
+		p.peekError(token.IDENT)
+		return nil
+	}
+	param := &ast.Parameter{}
+	param.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.COLON) {
+		return nil
+	}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	param.Type = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	params = append(params, param)
+
+	// Subsequent parameters
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken() // Consume COMMA
+		p.nextToken() // Move to the next parameter name
+
+		if p.curToken.Type != token.IDENT {
+			p.peekError(token.IDENT)
+			return nil
+		}
+		param := &ast.Parameter{}
+		param.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		param.Type = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		params = append(params, param)
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	return params
+}
+
+func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
+	stmt := &ast.ExpressionStatement{Token: p.curToken}
+	stmt.Expression = p.parseExpression(LOWEST)
+
+	return stmt
+}
+
+func (p *Parser) parseExpression(precedence int) ast.Expression {
+	prefix := p.prefixParseFns[p.curToken.Type]
+	if prefix == nil {
+		p.noPrefixParseFnError(p.curToken.Type)
+		return nil
+	}
+	leftExp := prefix()
+
+	for precedence < p.peekPrecedence() {
+		infix := p.infixParseFns[p.peekToken.Type]
+		if infix == nil {
+			return leftExp
+		}
+		p.nextToken()
+		leftExp = infix(leftExp)
+	}
+
+	return leftExp
+}
+
+// --- Prefix Parsing Functions ---
+
+func (p *Parser) parseIdentifier() ast.Expression {
+	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+}
+
+func (p *Parser) parseIntegerLiteral() ast.Expression {
+	lit := &ast.IntegerLiteral{Token: p.curToken}
+	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	if err != nil {
+		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+	lit.Value = value
+	return lit
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.curToken}
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if err != nil {
+		msg := fmt.Sprintf("could not parse %q as float", p.curToken.Literal)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+	lit.Value = value
+	return lit
+}
+
+func (p *Parser) parseStringLiteral() ast.Expression {
+	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+}
+
+func (p *Parser) parseBooleanLiteral() ast.Expression {
+	return &ast.BooleanLiteral{Token: p.curToken, Value: p.curToken.Type == token.TRUE}
+}
+
+func (p *Parser) parseNilLiteral() ast.Expression {
+	return &ast.NilLiteral{Token: p.curToken}
+}
+
+func (p *Parser) parseGroupedExpression() ast.Expression {
+	p.nextToken()
+	exp := p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+	return exp
+}
+
+func (p *Parser) parseListLiteral() ast.Expression {
+	lit := &ast.ListLiteral{Token: p.curToken}
+	lit.Elements = p.parseExpressionList(token.RBRACKET)
+	return lit
+}
+
+func (p *Parser) parseMapLiteral() ast.Expression {
+	lit := &ast.MapLiteral{Token: p.curToken}
+	lit.Pairs = make(map[ast.Expression]ast.Expression)
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		key := p.parseExpression(LOWEST)
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+
+		p.nextToken()
+		value := p.parseExpression(LOWEST)
+
+		lit.Pairs[key] = value
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return lit
+}
+
+func (p *Parser) parseIfExpression() ast.Expression {
+	expression := &ast.IfExpression{Token: p.curToken}
+	p.nextToken()
+	expression.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.THEN) {
+		return nil
+	}
+
+	p.nextToken()
+	expression.Consequence = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.ELSE) {
+		p.nextToken()
+		p.nextToken()
+		expression.Alternative = p.parseExpression(LOWEST)
+	}
+
+	return expression
+}
+
+func (p *Parser) parseForExpression() ast.Expression {
+	expression := &ast.ForExpression{Token: p.curToken}
+
+	// for x in xs if x > 0, y in ys then ...
+	for {
+		generator := p.parseForGenerator()
+		if generator == nil {
+			return nil
+		}
+		expression.Generators = append(expression.Generators, generator)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.THEN) {
+		return nil
+	}
+
+	p.nextToken()
+	expression.Body = p.parseExpression(LOWEST)
+
+	// for ... then key: value
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		p.nextToken()
+		expression.MapKey = expression.Body
+		expression.Body = p.parseExpression(LOWEST)
+	}
+
+	return expression
+}
+
+func (p *Parser) parseForGenerator() *ast.ForGenerator {
+	generator := &ast.ForGenerator{}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	generator.Variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	// k, v in m
+	if p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		generator.Key = generator.Variable
+		generator.Variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+
+	p.nextToken()
+	generator.Collection = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.IF) {
+		p.nextToken()
+		p.nextToken()
+		generator.Condition = p.parseExpression(LOWEST)
+	}
+
+	return generator
+}
+
+func (p *Parser) parseMatchExpression() ast.Expression {
+	expression := &ast.MatchExpression{Token: p.curToken}
+
+	p.nextToken()
+	expression.Subject = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil // Missing '{'
+	}
+
+	for !p.peekTokenIs(token.RBRACE) && !p.peekTokenIs(token.EOF) {
+		p.nextToken()
+
+		switch p.curToken.Type {
+		case token.IS:
+			p.nextToken() // consume 'is'
+			condition := p.parseExpression(LOWEST)
+
+			if !p.expectPeek(token.THEN) {
+				return nil // Missing 'then'
+			}
+
+			p.nextToken() // consume 'then'
+			consequence := p.parseExpression(LOWEST)
+			expression.Cases = append(expression.Cases, &ast.MatchCase{Condition: condition, Consequence: consequence})
+
+		case token.DEFAULT:
+			p.nextToken()
+			expression.Default = p.parseExpression(LOWEST)
+
+		default:
+			// Invalid token inside match block
+			msg := fmt.Sprintf("unexpected token in match block: got %s, expected 'is' or 'default'", p.curToken.Type)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil // Missing '}'
+	}
+
+	return expression
+}
+
+func (p *Parser) parsePrefixExpression() ast.Expression {
+	expression := &ast.PrefixExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+	}
+	p.nextToken()
+	expression.Right = p.parseExpression(PREFIX)
+	return expression
+}
+
+// parseYieldExpression parses `yield <expression>` inside a supp body.
+func (p *Parser) parseYieldExpression() ast.Expression {
+	exp := &ast.YieldExpression{Token: p.curToken}
+	p.nextToken()
+	exp.Value = p.parseExpression(LOWEST)
+	return exp
+}
+
+// parseSpawnExpression parses `spawn <call>`.
+func (p *Parser) parseSpawnExpression() ast.Expression {
+	exp := &ast.SpawnExpression{Token: p.curToken}
+	p.nextToken()
+	call, ok := p.parseExpression(LOWEST).(*ast.CallExpression)
+	if !ok {
+		p.errors = append(p.errors, "spawn must be followed by a call, e.g. spawn worker(ch)")
+		return nil
+	}
+	exp.Call = call
+	return exp
+}
+
+// parseSelectExpression parses a select block. Each case is a call to recv or send,
+// optionally naming the received value:
+//
+//	select { is recv(in) as x then handle(x) is send(out, 1) then nil default idle() }
+func (p *Parser) parseSelectExpression() ast.Expression {
+	expression := &ast.SelectExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RBRACE) && !p.peekTokenIs(token.EOF) {
+		p.nextToken()
+
+		switch p.curToken.Type {
+		case token.IS:
+			p.nextToken()
+			call, _ := p.parseExpression(LOWEST).(*ast.CallExpression)
+			var name *ast.Identifier
+			if call != nil {
+				name, _ = call.Function.(*ast.Identifier)
+			}
+			switch {
+			case name == nil:
+				p.errors = append(p.errors, "select case must be a call to recv or send")
+				return nil
+			case name.Value == "recv" && len(call.Arguments) != 1:
+				p.errors = append(p.errors, "select case recv takes one channel")
+				return nil
+			case name.Value == "send" && len(call.Arguments) != 2:
+				p.errors = append(p.errors, "select case send takes a channel and a value")
+				return nil
+			case name.Value != "recv" && name.Value != "send":
+				p.errors = append(p.errors, fmt.Sprintf("select case must be a call to recv or send, got %s", name.Value))
+				return nil
+			}
+			c := &ast.SelectCase{Call: call}
+
+			if p.peekTokenIs(token.AS) {
+				if name.Value == "send" {
+					p.errors = append(p.errors, "select case send has no value to bind with as")
+					return nil
+				}
+				p.nextToken()
+				if !p.expectPeek(token.IDENT) {
+					return nil
+				}
+				c.Variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			}
+
+			if !p.expectPeek(token.THEN) {
+				return nil
+			}
+			p.nextToken()
+			c.Body = p.parseExpression(LOWEST)
+			expression.Cases = append(expression.Cases, c)
+
+		case token.DEFAULT:
+			p.nextToken()
+			expression.Default = p.parseExpression(LOWEST)
+
+		default:
+			msg := fmt.Sprintf("unexpected token in select block: got %s, expected 'is' or 'default'", p.curToken.Type)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return expression
+}
+
+func (p *Parser) parseFailExpression() ast.Expression {
+	exp := &ast.FailExpression{Token: p.curToken}
+
+	// The 'fail' keyword must be followed by a string literal.
+	if !p.expectPeek(token.STRING) {
+		// If not a string, we can't create a valid FailExpression.
+		// The error is already recorded by expectPeek.
+		return nil
+	}
+
+	// The current token is now the string literal.
+	// We can get its value directly.
+	exp.Message = p.curToken.Literal
+
+	return exp
+}
+
+// --- Infix Parsing Functions ---
+
+func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
+	expression := &ast.InfixExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+		Left:     left,
+	}
+	precedence := p.curPrecedence()
+	p.nextToken()
+	expression.Right = p.parseExpression(precedence)
+	return expression
+}
+
+func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
+	exp := &ast.CallExpression{Token: p.curToken, Function: function}
+	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	return exp
+}
+
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+
+	p.nextToken()
+	exp.Index = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return exp
+}
+
+// parseMemberExpression parses `module.name`. Only imported modules have members,
+// so the left side must be a name.
+func (p *Parser) parseMemberExpression(left ast.Expression) ast.Expression {
+	exp := &ast.MemberExpression{Token: p.curToken}
+	module, ok := left.(*ast.Identifier)
+	if !ok {
+		p.errors = append(p.errors, fmt.Sprintf("only a module name can be followed by ., got %s", left))
+		return nil
+	}
+	exp.Module = module
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	exp.Member = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	return exp
+}
+
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	list := []ast.Expression{}
+
+	if p.peekTokenIs(end) {
+		p.nextToken()
+		return list
+	}
+
+	p.nextToken()
+	list = append(list, p.parseExpression(LOWEST))
+
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.nextToken()
+		list = append(list, p.parseExpression(LOWEST))
+	}
+
+	if !p.expectPeek(end) {
+		return nil
+	}
+
+	return list
+}
+
+// --- Helper Methods ---
+
+func (p *Parser) curTokenIs(t token.TokenType) bool {
+	return p.curToken.Type == t
+}
+
+func (p *Parser) peekTokenIs(t token.TokenType) bool {
+	return p.peekToken.Type == t
+}
+
+func (p *Parser) expectPeek(t token.TokenType) bool {
+	if p.peekTokenIs(t) {
+		p.nextToken()
+		return true
+	}
+	p.peekError(t)
+	return false
+}
+
+func (p *Parser) peekPrecedence() int {
+	if p, ok := precedences[p.peekToken.Type]; ok {
+		return p
+	}
+	return LOWEST
+}
+
+func (p *Parser) curPrecedence() int {
+	if p, ok := precedences[p.curToken.Type]; ok {
+		return p
+	}
+	return LOWEST
+}
+
+func (p *Parser) registerPrefix(tokenType token.TokenType, fn prefixParseFn) {
+	p.prefixParseFns[tokenType] = fn
+}
+
+func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
+	p.infixParseFns[tokenType] = fn
+}
+
+func (p *Parser) noPrefixParseFnError(t token.TokenType) {
+	msg := fmt.Sprintf("no prefix parse function for %s found", t)
+	p.errors = append(p.errors, msg)
+}
+
+func (p *Parser) Errors() []string {
+	return p.errors
+}
+
+func (p *Parser) peekError(t token.TokenType) {
+	msg := fmt.Sprintf("expected next token to be %s, got %s instead",
+		t, p.peekToken.Type)
+	p.errors = append(p.errors, msg)
+}
@@ -1,4 +1,5 @@
-package main
+// Package token defines the tokens produced by the Duet lexer.
+package token
 
 // TokenType is a string representing the type of a token.
 type TokenType string
@@ -21,21 +22,22 @@ const (
 	STRING = "STRING" // "hello world"
 
 	// Operators
-	ASSIGN   = "="
-	PLUS     = "+"
-	MINUS    = "-"
-	BANG     = "!"
-	ASTERISK = "*"
-	SLASH    = "/"
-	MODULO   = "%"
-	LT       = "<"
-	GT       = ">"
-	LE       = "<="
-	GE       = ">="
-	EQ       = "=="
-	NOT_EQ   = "!="
-	ARROW    = "->"
-	PIPELINE = "|>"
+	ASSIGN            = "="
+	PLUS              = "+"
+	MINUS             = "-"
+	BANG              = "!"
+	ASTERISK          = "*"
+	SLASH             = "/"
+	MODULO            = "%"
+	LT                = "<"
+	GT                = ">"
+	LE                = "<="
+	GE                = ">="
+	EQ                = "=="
+	NOT_EQ            = "!="
+	ARROW             = "->"
+	PIPELINE          = "|>"
+	PARALLEL_PIPELINE = "||>"
 
 	// Delimiters
 	COMMA    = ","
@@ -46,6 +48,8 @@ const (
 	RBRACE   = "}"
 	LBRACKET = "["
 	RBRACKET = "]"
+	AT       = "@"
+	DOT      = "."
 
 	// Keywords
 	PROC    = "PROC"
@@ -63,6 +67,11 @@ const (
 	TRUE    = "TRUE"
 	FALSE   = "FALSE"
 	NIL     = "NIL"
+	YIELD   = "YIELD"
+	SPAWN   = "SPAWN"
+	SELECT  = "SELECT"
+	AS      = "AS"
+	IMPORT  = "IMPORT"
 )
 
 var keywords = map[string]TokenType{
@@ -81,6 +90,11 @@ var keywords = map[string]TokenType{
 	"true":    TRUE,
 	"false":   FALSE,
 	"nil":     NIL,
+	"yield":   YIELD,
+	"spawn":   SPAWN,
+	"select":  SELECT,
+	"as":      AS,
+	"import":  IMPORT,
 }
 
 // LookupIdent checks the keywords table to see whether the given identifier is a keyword.